@@ -1,36 +1,56 @@
 package client
 
 import (
+	"net/http"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/orov-io/BlackBart/response"
-	api "github.com/orov-io/BlackBeard"
 )
 
 const (
 	portkey      = "PORT"
 	serviceKey   = "SERVICE_BASE_PATH"
+	baseURLKey   = "SERVICE_BASE_URL"
 	v1           = "v1"
 	pingEndpoint = "/ping"
 )
 
 var service = os.Getenv(serviceKey)
 
-// Ping make a call to the is_alive endpoint.
+// Ping make a call to the is_alive endpoint. The request is sent by HTTPClient
+// on the context of c, so the request ID and the trace are forwarded.
 func Ping(c *gin.Context) (*PongResponse, error) {
-	client := api.MakeNewClient().WithDefaultBasePath().WithVersion(v1).
-		ToService(service)
-	resp, err := client.GET(pingEndpoint, nil)
+	req, err := NewRequest(c, http.MethodGet, getServiceURL(v1, service, pingEndpoint), nil)
 	if err != nil {
 		return nil, err
 	}
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
 	pong := new(PongResponse)
 	err = response.ParseTo(resp, &pong)
 
 	return pong, err
 }
 
+// getServiceURL joins the elements to the SERVICE_BASE_URL env variable, that
+// defaults to the local service listening on PORT.
+func getServiceURL(elements ...string) string {
+	baseURL := os.Getenv(baseURLKey)
+	if baseURL == "" {
+		baseURL = "http://localhost:" + os.Getenv(portkey)
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + path.Join(append([]string{"/"}, elements...)...)
+}
+
 // PongResponse is the expected response to ping request
 type PongResponse struct {
 	Status  string `json:"status,omitempty"`
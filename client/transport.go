@@ -0,0 +1,83 @@
+package client
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/requestid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/orov-io/BlackBart/client"
+
+// Transport is an http.RoundTripper that forwards the request ID carried by
+// the outbound request context on the X-Request-ID header. If tracing is
+// enabled, it also traces the call and injects the trace context headers.
+type Transport struct {
+	// Base is the wrapped RoundTripper. http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.String()),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	if id := requestid.FromContext(ctx); id != "" && req.Header.Get(requestid.Header) == "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// HTTPClient is an http.Client that forwards the request ID on every call.
+var HTTPClient = &http.Client{Transport: &Transport{}}
+
+// NewRequest returns an http.Request bound to the context of the incoming gin
+// request, so the request ID is forwarded when it is sent by HTTPClient.
+func NewRequest(c *gin.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil && c.Request != nil {
+		req = req.WithContext(c.Request.Context())
+		if id := requestid.Get(c); id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+	}
+
+	return req, nil
+}
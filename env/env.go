@@ -0,0 +1,35 @@
+// Package env defines the environments a service runs on, declared with the
+// ENV env variable, so all the packages agree on them.
+package env
+
+import (
+	"os"
+	"strings"
+)
+
+// Key is the env variable that declares the environment.
+const Key = "ENV"
+
+// The known environments.
+const (
+	Local         = "local"
+	Development   = "dev"
+	PreProduction = "pre"
+	Production    = "prod"
+)
+
+// Get returns the declared environment, in lower case.
+func Get() string {
+	return strings.ToLower(strings.TrimSpace(os.Getenv(Key)))
+}
+
+// Is checks if the declared environment is the provided one. The comparison
+// is case insensitive.
+func Is(environment string) bool {
+	return Get() == strings.ToLower(environment)
+}
+
+// IsProduction checks if the service runs on production.
+func IsProduction() bool {
+	return Is(Production)
+}
@@ -0,0 +1,184 @@
+package kv
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+)
+
+// BadgerStore is a Store backed by a badger database.
+type BadgerStore struct {
+	db    *badger.DB
+	codec Codec
+}
+
+// NewBadgerStore returns a Store over the provided badger database. If codec is
+// nil, values are encoded as JSON.
+func NewBadgerStore(db *badger.DB, codec Codec) *BadgerStore {
+	return &BadgerStore{
+		db:    db,
+		codec: getCodec(codec),
+	}
+}
+
+// Get implements Store
+func (s *BadgerStore) Get(key string, receiver interface{}) error {
+	var value []byte
+	err := s.db.View(func(txn *badger.Txn) (err error) {
+		value, err = getBadgerValue(txn, key)
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.codec.Unmarshal(value, receiver)
+}
+
+// Set implements Store
+func (s *BadgerStore) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(newBadgerEntry(key, data, ttl))
+	})
+}
+
+// Delete implements Store
+func (s *BadgerStore) Delete(key string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+// List implements Store
+func (s *BadgerStore) List(prefix string, cursor string, limit int) (*Page, error) {
+	limit = getPageSize(limit)
+	page := &Page{Items: make([]*Item, 0, limit)}
+	start := prefix
+	if cursor != "" {
+		start = cursor
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		options := badger.DefaultIteratorOptions
+		options.Prefix = []byte(prefix)
+		it := txn.NewIterator(options)
+		defer it.Close()
+
+		for it.Seek([]byte(start)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			key := string(it.Item().KeyCopy(nil))
+			if len(page.Items) == limit {
+				page.Next = key
+				return nil
+			}
+
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			page.Items = append(page.Items, &Item{Key: key, value: value, codec: s.codec})
+		}
+		return nil
+	})
+
+	return page, err
+}
+
+// CompareAndSet implements Store
+func (s *BadgerStore) CompareAndSet(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	var expected []byte
+	if old != nil {
+		expected, err = s.codec.Marshal(old)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	swapped := false
+	err = s.db.Update(func(txn *badger.Txn) error {
+		current, err := getBadgerValue(txn, key)
+		if err != nil && !IsNotFoundError(err) {
+			return err
+		}
+
+		exists := err == nil
+		if exists != (old != nil) || !bytes.Equal(current, expected) {
+			return nil
+		}
+
+		swapped = true
+		return txn.SetEntry(newBadgerEntry(key, data, ttl))
+	})
+	if err == badger.ErrConflict {
+		return false, nil
+	}
+
+	return swapped && err == nil, err
+}
+
+// Increment implements Store
+func (s *BadgerStore) Increment(key string, delta int64) (int64, error) {
+	var counter int64
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		switch {
+		case err == badger.ErrKeyNotFound:
+			counter = 0
+		case err != nil:
+			return err
+		default:
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			counter, err = strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return NewNotACounterError(key)
+			}
+		}
+
+		counter += delta
+		entry := badger.NewEntry([]byte(key), []byte(strconv.FormatInt(counter, 10)))
+		if item != nil {
+			// Keep the absolute expiration: recomputing a ttl from it can
+			// round to the past and drop the counter.
+			entry.ExpiresAt = item.ExpiresAt()
+		}
+		return txn.SetEntry(entry)
+	})
+
+	return counter, err
+}
+
+func getBadgerValue(txn *badger.Txn, key string) ([]byte, error) {
+	item, err := txn.Get([]byte(key))
+	if err == badger.ErrKeyNotFound {
+		return nil, NewNotFoundError(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item.ValueCopy(nil)
+}
+
+func newBadgerEntry(key string, value []byte, ttl time.Duration) *badger.Entry {
+	entry := badger.NewEntry([]byte(key), value)
+	if ttl > 0 {
+		// badger expirations have second granularity, so round up to not
+		// expire keys with a sub second ttl as soon as they are written.
+		entry.ExpiresAt = uint64(time.Now().Add(ttl + time.Second - 1).Unix())
+	}
+	return entry
+}
@@ -0,0 +1,150 @@
+package kv
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+)
+
+func newTestBadgerStore(t *testing.T) *BadgerStore {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("unable to open badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewBadgerStore(db, nil)
+}
+
+func TestBadgerStoreGetSetDelete(t *testing.T) {
+	store := newTestBadgerStore(t)
+
+	if err := store.Set("key", map[string]string{"foo": "bar"}, 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var value map[string]string
+	if err := store.Get("key", &value); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if value["foo"] != "bar" {
+		t.Errorf("Get = %v, want foo=bar", value)
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Get("key", &value); !IsNotFoundError(err) {
+		t.Errorf("Get after Delete = %v, want NotFoundError", err)
+	}
+}
+
+func TestBadgerStoreSubSecondTTL(t *testing.T) {
+	store := newTestBadgerStore(t)
+
+	if err := store.Set("key", "value", time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var value string
+	if err := store.Get("key", &value); err != nil {
+		t.Errorf("Get just after Set with a sub second ttl: %v", err)
+	}
+}
+
+func TestBadgerStoreList(t *testing.T) {
+	store := newTestBadgerStore(t)
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		if err := store.Set(key, key, 0); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	page, err := store.List("a/", "", 2)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 2 || page.Next != "a/3" {
+		t.Fatalf("List first page = %d items, next %q; want 2 items, next \"a/3\"", len(page.Items), page.Next)
+	}
+
+	page, err = store.List("a/", page.Next, 2)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Items) != 1 || page.Next != "" {
+		t.Fatalf("List last page = %d items, next %q; want 1 item, no next", len(page.Items), page.Next)
+	}
+
+	var value string
+	if err := page.Items[0].Decode(&value); err != nil || value != "a/3" {
+		t.Errorf("Decode = %q, %v; want \"a/3\"", value, err)
+	}
+}
+
+func TestBadgerStoreCompareAndSet(t *testing.T) {
+	store := newTestBadgerStore(t)
+
+	tests := []struct {
+		name  string
+		old   interface{}
+		value interface{}
+		want  bool
+	}{
+		{"create missing key", nil, "first", true},
+		{"create existing key", nil, "other", false},
+		{"swap with wrong value", "other", "second", false},
+		{"swap with current value", "first", "second", true},
+	}
+
+	for _, test := range tests {
+		swapped, err := store.CompareAndSet("key", test.old, test.value, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if swapped != test.want {
+			t.Errorf("%s: swapped = %v, want %v", test.name, swapped, test.want)
+		}
+	}
+
+	var value string
+	if err := store.Get("key", &value); err != nil || value != "second" {
+		t.Errorf("Get = %q, %v; want \"second\"", value, err)
+	}
+}
+
+func TestBadgerStoreIncrement(t *testing.T) {
+	store := newTestBadgerStore(t)
+
+	if err := store.Set("counter", 1, time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	counter, err := store.Increment("counter", 2)
+	if err != nil || counter != 3 {
+		t.Fatalf("Increment = %d, %v; want 3", counter, err)
+	}
+
+	err = store.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("counter"))
+		if err != nil {
+			return err
+		}
+		if item.ExpiresAt() == 0 {
+			t.Error("Increment dropped the counter expiration")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("counter lost after Increment: %v", err)
+	}
+
+	if err := store.Set("text", "foo", 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if _, err := store.Increment("text", 1); !IsNotACounterError(err) {
+		t.Errorf("Increment over a non counter = %v, want NotACounterError", err)
+	}
+}
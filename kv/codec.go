@@ -0,0 +1,41 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec encodes and decodes values stored on a Store.
+type Codec interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, receiver interface{}) error
+}
+
+// Provided codecs
+var (
+	JSON Codec = jsonCodec{}
+	Gob  Codec = gobCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, receiver interface{}) error {
+	return json.Unmarshal(data, receiver)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(value)
+	return buffer.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, receiver interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(receiver)
+}
@@ -0,0 +1,43 @@
+package kv
+
+import "fmt"
+
+// NotFoundError is used when the requested key does not exist.
+type NotFoundError struct {
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Key %v not found", e.Key)
+}
+
+// NewNotFoundError returns a new NotFoundError error.
+func NewNotFoundError(key string) error {
+	return &NotFoundError{key}
+}
+
+// IsNotFoundError checks if the error is a NotFoundError error.
+func IsNotFoundError(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// NotACounterError is used when Increment is called over a non numeric value.
+type NotACounterError struct {
+	Key string
+}
+
+func (e *NotACounterError) Error() string {
+	return fmt.Sprintf("Value stored on key %v is not a counter", e.Key)
+}
+
+// NewNotACounterError returns a new NotACounterError error.
+func NewNotACounterError(key string) error {
+	return &NotACounterError{key}
+}
+
+// IsNotACounterError checks if the error is a NotACounterError error.
+func IsNotACounterError(err error) bool {
+	_, ok := err.(*NotACounterError)
+	return ok
+}
@@ -0,0 +1,62 @@
+// Package kv provides a typed key/value store API that can be backed either by
+// the service internal badger database or by a redis pool.
+package kv
+
+import "time"
+
+// DefaultPageSize is the page size used by List when limit is not positive.
+const DefaultPageSize = 100
+
+// Store models a key/value store. Values are encoded with the store Codec, so
+// any value accepted by the codec can be stored and retrieved.
+type Store interface {
+	// Get decodes the value stored under key into receiver, that must be a
+	// pointer. Returns a NotFoundError if the key does not exist.
+	Get(key string, receiver interface{}) error
+	// Set stores value under key. A zero ttl means that the key never expires.
+	Set(key string, value interface{}, ttl time.Duration) error
+	// Delete removes the key. Deleting a missing key is not an error.
+	Delete(key string) error
+	// List returns up to limit items whose keys start with prefix. Pass the
+	// Next field of the returned page as cursor to fetch the following page.
+	List(prefix string, cursor string, limit int) (*Page, error)
+	// CompareAndSet atomically stores value under key only if the current
+	// value equals old. A nil old means that the key must not exist.
+	CompareAndSet(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error)
+	// Increment atomically adds delta to the counter stored under key and
+	// returns the new value. Missing counters start at zero.
+	Increment(key string, delta int64) (int64, error)
+}
+
+// Page is a page of items returned by Store.List
+type Page struct {
+	Items []*Item
+	// Next is the cursor to fetch the next page. It is empty on the last page.
+	Next string
+}
+
+// Item is a raw key/value pair returned by Store.List
+type Item struct {
+	Key   string
+	value []byte
+	codec Codec
+}
+
+// Decode decodes the item value into receiver, that must be a pointer.
+func (i *Item) Decode(receiver interface{}) error {
+	return i.codec.Unmarshal(i.value, receiver)
+}
+
+func getPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return limit
+}
+
+func getCodec(codec Codec) Codec {
+	if codec == nil {
+		return JSON
+	}
+	return codec
+}
@@ -0,0 +1,191 @@
+package kv
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	redisGet    = "GET"
+	redisSet    = "SET"
+	redisDel    = "DEL"
+	redisScan   = "SCAN"
+	redisMGet   = "MGET"
+	redisIncrBy = "INCRBY"
+	redisPX     = "PX"
+	redisMatch  = "MATCH"
+	redisCount  = "COUNT"
+	redisCursor = "0"
+)
+
+// compareAndSetScript sets KEYS[1] to ARGV[1] only if it stores ARGV[2]. If
+// ARGV[3] is "1", the key must not exist. ARGV[4] is the ttl in milliseconds.
+var compareAndSetScript = redis.NewScript(1, `
+local current = redis.call('GET', KEYS[1])
+if ARGV[3] == '1' then
+	if current then return 0 end
+elseif current ~= ARGV[2] then
+	return 0
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[4])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// RedisStore is a Store backed by a redis pool.
+type RedisStore struct {
+	pool  *redis.Pool
+	codec Codec
+}
+
+// NewRedisStore returns a Store over the provided redis pool. If codec is nil,
+// values are encoded as JSON.
+func NewRedisStore(pool *redis.Pool, codec Codec) *RedisStore {
+	return &RedisStore{
+		pool:  pool,
+		codec: getCodec(codec),
+	}
+}
+
+// Get implements Store
+func (s *RedisStore) Get(key string, receiver interface{}) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do(redisGet, key))
+	if err == redis.ErrNil {
+		return NewNotFoundError(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.codec.Unmarshal(value, receiver)
+}
+
+// Set implements Store
+func (s *RedisStore) Set(key string, value interface{}, ttl time.Duration) error {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{}.Add(key, data)
+	if ttl > 0 {
+		args = args.Add(redisPX, toMilliseconds(ttl))
+	}
+	_, err = conn.Do(redisSet, args...)
+	return err
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(key string) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do(redisDel, key)
+	return err
+}
+
+// List implements Store. As redis SCAN is used, a page can contain less than
+// limit items even if it is not the last one.
+func (s *RedisStore) List(prefix string, cursor string, limit int) (*Page, error) {
+	limit = getPageSize(limit)
+	if cursor == "" {
+		cursor = redisCursor
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do(redisScan, cursor, redisMatch, escapeRedisPattern(prefix)+"*", redisCount, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	if _, err = redis.Scan(reply, &cursor, &keys); err != nil {
+		return nil, err
+	}
+
+	page := &Page{Items: make([]*Item, 0, len(keys))}
+	if cursor != redisCursor {
+		page.Next = cursor
+	}
+	if len(keys) == 0 {
+		return page, nil
+	}
+
+	values, err := redis.ByteSlices(conn.Do(redisMGet, redis.Args{}.AddFlat(keys)...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		// Key can expire between SCAN and MGET
+		if values[i] == nil {
+			continue
+		}
+		page.Items = append(page.Items, &Item{Key: key, value: values[i], codec: s.codec})
+	}
+
+	return page, nil
+}
+
+// CompareAndSet implements Store
+func (s *RedisStore) CompareAndSet(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	mustNotExist := "1"
+	var expected []byte
+	if old != nil {
+		mustNotExist = "0"
+		expected, err = s.codec.Marshal(old)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(compareAndSetScript.Do(conn, key, data, expected, mustNotExist, toMilliseconds(ttl)))
+}
+
+// Increment implements Store
+func (s *RedisStore) Increment(key string, delta int64) (int64, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	counter, err := redis.Int64(conn.Do(redisIncrBy, key, delta))
+	if err != nil && strings.Contains(err.Error(), "not an integer") {
+		return 0, NewNotACounterError(key)
+	}
+	return counter, err
+}
+
+// toMilliseconds rounds ttl up to the next millisecond, as PX 0 is rejected by
+// redis and would make the CompareAndSet script store the key without expiration.
+func toMilliseconds(ttl time.Duration) string {
+	if ttl <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64((ttl+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+func escapeRedisPattern(pattern string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+	return replacer.Replace(pattern)
+}
@@ -0,0 +1,32 @@
+package kv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToMilliseconds(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, "0"},
+		{-time.Second, "0"},
+		{time.Microsecond, "1"},
+		{time.Millisecond, "1"},
+		{1500 * time.Microsecond, "2"},
+		{time.Second, "1000"},
+	}
+
+	for _, test := range tests {
+		if got := toMilliseconds(test.ttl); got != test.want {
+			t.Errorf("toMilliseconds(%v) = %q, want %q", test.ttl, got, test.want)
+		}
+	}
+}
+
+func TestEscapeRedisPattern(t *testing.T) {
+	if got, want := escapeRedisPattern(`a*b?[c]\`), `a\*b\?\[c\]\\`; got != want {
+		t.Errorf("escapeRedisPattern = %q, want %q", got, want)
+	}
+}
@@ -2,17 +2,15 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/env"
 	"github.com/orov-io/BlackBart/response"
 	"github.com/orov-io/BlackBart/server"
 )
 
 const (
-	envKey  = "ENV"
 	portKey = "PORT"
-	local   = "local"
 )
 
 func main() {
@@ -23,9 +21,7 @@ func main() {
 
 	addRoutes(service)
 
-	environment := os.Getenv(envKey)
-
-	if environment == local {
+	if env.Is(env.Local) {
 		err = service.Run(":" + server.GetEnvPort(portKey))
 	} else {
 		err = nil
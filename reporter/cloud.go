@@ -0,0 +1,94 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const cloudErrorReportingType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// CloudErrorReporting writes events as JSON lines in the Cloud Error Reporting
+// log format. When written to stdout on App Engine, Cloud Run or GKE, Cloud
+// Logging ingests them and Error Reporting groups them by their stack trace.
+type CloudErrorReporting struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewCloudErrorReporting returns a CloudErrorReporting reporter that writes to w.
+func NewCloudErrorReporting(w io.Writer) *CloudErrorReporting {
+	return &CloudErrorReporting{encoder: json.NewEncoder(w)}
+}
+
+type cloudEntry struct {
+	Type           string              `json:"@type"`
+	Severity       string              `json:"severity"`
+	EventTime      string              `json:"eventTime"`
+	Message        string              `json:"message"`
+	ServiceContext cloudServiceContext `json:"serviceContext"`
+	Context        cloudContext        `json:"context"`
+	Trace          string              `json:"logging.googleapis.com/trace,omitempty"`
+	Labels         map[string]string   `json:"logging.googleapis.com/labels,omitempty"`
+}
+
+type cloudServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+type cloudContext struct {
+	HTTPRequest *cloudHTTPRequest `json:"httpRequest,omitempty"`
+	User        string            `json:"user,omitempty"`
+}
+
+type cloudHTTPRequest struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int    `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+// Report implements Reporter
+func (r *CloudErrorReporting) Report(event *Event) {
+	entry := cloudEntry{
+		Type:      cloudErrorReportingType,
+		Severity:  "ERROR",
+		EventTime: event.Time.UTC().Format(time.RFC3339Nano),
+		// Error Reporting needs the stack trace on the message to group events.
+		Message: fmt.Sprintf("%v\n\n%s", event.Err, event.Stack),
+		ServiceContext: cloudServiceContext{
+			Service: event.Service,
+			Version: event.Version,
+		},
+		Context: cloudContext{User: event.User},
+		Trace:   event.Trace,
+	}
+
+	if event.TraceID != "" {
+		entry.Labels = map[string]string{"trace_id": event.TraceID}
+	}
+
+	if req := event.Request; req != nil {
+		entry.Context.HTTPRequest = &cloudHTTPRequest{
+			Method:             req.Method,
+			URL:                req.URL.String(),
+			UserAgent:          req.UserAgent(),
+			Referrer:           req.Referer(),
+			ResponseStatusCode: event.Status,
+			RemoteIP:           event.RemoteIP,
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.encoder.Encode(entry); err != nil {
+		// Reporting can't fail the request, so we only can warn on stderr.
+		fmt.Fprintf(os.Stderr, "Can't report error %v: %v\n", event.Err, err)
+	}
+}
@@ -0,0 +1,52 @@
+package reporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Local writes events in a human readable format. Use it on development.
+type Local struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewLocal returns a Local reporter that writes to w.
+func NewLocal(w io.Writer) *Local {
+	return &Local{w: w}
+}
+
+// NewFile returns a Local reporter that appends events to the file at path.
+func NewFile(path string) (*Local, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocal(file), nil
+}
+
+// Report implements Reporter
+func (r *Local) Report(event *Event) {
+	kind := "ERROR"
+	if event.Panic {
+		kind = "PANIC"
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fmt.Fprintf(r.w, "[%v] %v %v/%v: %v\n", event.Time.Format(time.RFC3339), kind, event.Service, event.Version, event.Err)
+	if req := event.Request; req != nil {
+		fmt.Fprintf(r.w, "\trequest: %v %v -> %v\n", req.Method, req.URL, event.Status)
+	}
+	if event.TraceID != "" {
+		fmt.Fprintf(r.w, "\ttrace: %v\n", event.TraceID)
+	}
+	if event.User != "" {
+		fmt.Fprintf(r.w, "\tuser: %v\n", event.User)
+	}
+	fmt.Fprintf(r.w, "%s\n", event.Stack)
+}
@@ -0,0 +1,50 @@
+// Package reporter sends server errors and panics, with their request context,
+// to an error reporting backend.
+package reporter
+
+import (
+	"net/http"
+	"time"
+)
+
+// Reporter receives server errors and panics.
+type Reporter interface {
+	Report(event *Event)
+}
+
+// Event models a reported error.
+type Event struct {
+	Time  time.Time
+	Err   error
+	Stack []byte
+	Panic bool
+	// TraceID is the ID sent to the client on hidden errors.
+	TraceID string
+	// Trace is the Cloud Trace resource name of the request, as
+	// projects/<project>/traces/<trace id>.
+	Trace    string
+	Request  *http.Request
+	RemoteIP string
+	// Status is the http status sent to the client.
+	Status  int
+	User    string
+	Service string
+	Version string
+}
+
+// Func adapts a function to the Reporter interface.
+type Func func(event *Event)
+
+// Report implements Reporter
+func (f Func) Report(event *Event) {
+	f(event)
+}
+
+// Multi returns a Reporter that sends each event to all the provided reporters.
+func Multi(reporters ...Reporter) Reporter {
+	return Func(func(event *Event) {
+		for _, reporter := range reporters {
+			reporter.Report(event)
+		}
+	})
+}
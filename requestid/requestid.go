@@ -0,0 +1,76 @@
+// Package requestid propagates a request ID across logs, responses and
+// outbound calls, so one ID follows a request across services.
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// Header is the http header used to receive and forward the request ID.
+	Header = "X-Request-ID"
+	// Key is the key used to store the request ID on the gin context and the
+	// field name used on log entries.
+	Key = "request_id"
+
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// Middleware accepts the request ID sent by the client on the X-Request-ID
+// header or creates a new one. It stores the ID on the gin context and on the
+// request context, and sends it back on the response headers.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !isValid(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set(Key, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
+
+// Get returns the request ID attached to the gin context, or an empty string
+// if the Middleware is not in use.
+func Get(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return c.GetString(Key)
+}
+
+// NewContext returns a copy of ctx that carries the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// isValid discards empty, too long or non printable IDs, as they are written
+// to our logs and forwarded to other services.
+func isValid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, char := range id {
+		if char < '!' || char > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	codeExtension    = "code"
+	detailsExtension = "details"
+)
+
+// AppError models an application error with a stable code, the http status
+// to answer with and a message that is safe to send to the user. The cause is
+// only sent when errors are not hidden.
+type AppError struct {
+	Code    string
+	Status  int
+	Message string
+	Cause   error
+	Details map[string]interface{}
+}
+
+// Common application errors. Use WithCause and WithDetail to attach the
+// request specific info, as they return a copy of the error.
+var (
+	ErrBadRequest         = NewAppError("bad_request", http.StatusBadRequest, "The request is not valid")
+	ErrUnauthorized       = NewAppError("unauthorized", http.StatusUnauthorized, "You are no logged-in. Please, loggin")
+	ErrForbidden          = NewAppError("forbidden", http.StatusForbidden, "User has no enough permissions")
+	ErrNotFound           = NewAppError("not_found", http.StatusNotFound, "The resource does not exist")
+	ErrConflict           = NewAppError("conflict", http.StatusConflict, "The resource is in conflict with the request")
+	ErrInternal           = NewAppError("internal", http.StatusInternalServerError, "An error occurs on the server")
+	ErrServiceUnavailable = NewAppError("service_unavailable", http.StatusServiceUnavailable, "The service is temporarily unavailable")
+)
+
+// NewAppError returns a new AppError. Declare your catalog with it.
+func NewAppError(code string, status int, message string) *AppError {
+	return &AppError{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+// WithCause returns a copy of the error with the internal cause attached.
+func (e *AppError) WithCause(cause error) *AppError {
+	appErr := e.copy()
+	appErr.Cause = cause
+	return appErr
+}
+
+// WithDetail returns a copy of the error with the detail attached.
+func (e *AppError) WithDetail(key string, value interface{}) *AppError {
+	appErr := e.copy()
+	appErr.Details[key] = value
+	return appErr
+}
+
+func (e *AppError) copy() *AppError {
+	appErr := *e
+	appErr.Details = make(map[string]interface{}, len(e.Details)+1)
+	for key, value := range e.Details {
+		appErr.Details[key] = value
+	}
+	return &appErr
+}
+
+func (e *AppError) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+}
+
+// Unwrap returns the internal cause of the error.
+func (e *AppError) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether the target is an AppError with the same code, so errors
+// of the catalog can be checked with errors.Is after WithCause.
+func (e *AppError) Is(target error) bool {
+	appErr, ok := target.(*AppError)
+	return ok && appErr.Code == e.Code
+}
+
+// HTTPStatus returns the status of the error, 500 by default.
+func (e *AppError) HTTPStatus() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// AppErrorMapper is implemented by errors that are answered as an AppError of
+// the catalog by SendError, without being AppErrors themselves.
+type AppErrorMapper interface {
+	AppError() *AppError
+}
+
+// IsAppError checks if the error is, or wraps, an AppError
+func IsAppError(err error) bool {
+	var appErr *AppError
+	return errors.As(err, &appErr)
+}
+
+// SendError sends the error with the status it carries. AppErrors and
+// AppErrorMappers, even when wrapped, answer with their status, code, message
+// and details; their cause is hidden as any other error. *Error errors answer with their code. Any
+// other error is sent as an internal error.
+func SendError(c *gin.Context, err error) {
+	r := newResponse(c)
+	status := r.addAnyError(err)
+	if status >= http.StatusInternalServerError {
+		r.reportServerErrors(status)
+	}
+	r.sendError(status)
+}
+
+// addAnyError adds the error to the response and returns its http status.
+func (r *Response) addAnyError(err error) int {
+	var appErr *AppError
+	var mapper AppErrorMapper
+	if !errors.As(err, &appErr) && errors.As(err, &mapper) {
+		appErr = mapper.AppError()
+	}
+
+	if appErr != nil {
+		r.Code = appErr.Code
+		r.Message = appErr.Message
+		if len(appErr.Details) > 0 {
+			r.Details = appErr.Details
+		}
+		if appErr.Cause != nil {
+			r.addError(appErr.Cause)
+		}
+		return appErr.HTTPStatus()
+	}
+
+	r.addError(err)
+
+	var responseErr *Error
+	if errors.As(err, &responseErr) && responseErr.Code() >= http.StatusBadRequest {
+		return responseErr.Code()
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,115 @@
+package response
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// EnvelopeHeader flags the responses wrapped on an Envelope, so clients can
+// parse both modes.
+const EnvelopeHeader = "X-Response-Envelope"
+
+var envelope bool
+
+// SetEnvelope enables or disables the envelope mode. When enabled, success
+// and error responses share the Envelope shape. CSV responses are never
+// wrapped.
+func SetEnvelope(enabled bool) {
+	envelope = enabled
+}
+
+// Envelope models the shape of all the responses on envelope mode. Success
+// responses fill Data, and Meta for pages. Error responses fill Errors, and
+// Meta with the error code, message and details.
+type Envelope struct {
+	XMLName xml.Name    `json:"-" xml:"response" yaml:"-" codec:"-"`
+	Data    interface{} `json:"data,omitempty" xml:"data,omitempty" yaml:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty" xml:"meta,omitempty" yaml:"meta,omitempty"`
+	Errors  []string    `json:"errors,omitempty" xml:"errors>error,omitempty" yaml:"errors,omitempty"`
+}
+
+// errorEnvelope is used to parse error responses on envelope mode.
+type errorEnvelope struct {
+	Meta   *Response `json:"meta" xml:"meta" yaml:"meta"`
+	Errors []string  `json:"errors" xml:"errors>error" yaml:"errors"`
+}
+
+// xmlEnvelope is used to parse the data of success XML responses on envelope
+// mode.
+type xmlEnvelope struct {
+	Data struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"data"`
+}
+
+// wrap returns the envelope of the data. Responses are sent as errors and
+// pages are sent with their info on Meta.
+func wrap(data interface{}) *Envelope {
+	switch data := data.(type) {
+	case *Response:
+		return &Envelope{
+			Meta: &Response{
+				Code:    data.Code,
+				Message: data.Message,
+				Details: data.Details,
+			},
+			Errors: data.Errors,
+		}
+	case *Page:
+		return &Envelope{Data: data.Items, Meta: data.Page}
+	default:
+		return &Envelope{Data: data}
+	}
+}
+
+func isEnveloped(response *http.Response) bool {
+	enveloped, _ := strconv.ParseBool(response.Header.Get(EnvelopeHeader))
+	return enveloped
+}
+
+// parseErrorEnvelope decodes an error response on envelope mode.
+func parseErrorEnvelope(mediaType string, body []byte) (*Response, error) {
+	parsed := new(errorEnvelope)
+	err := decode(mediaType, body, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	r := parsed.Meta
+	if r == nil {
+		r = new(Response)
+	}
+	r.Errors = parsed.Errors
+	return r, nil
+}
+
+// unwrap decodes the data of an enveloped success response into the
+// receiver. The data is decoded generically and re-encoded in the same
+// format, as the receiver type is unknown to the envelope.
+func unwrap(mediaType string, body []byte, receiver interface{}) error {
+	if mediaType == binding.MIMEXML || mediaType == binding.MIMEXML2 {
+		parsed := new(xmlEnvelope)
+		err := xml.Unmarshal(body, parsed)
+		if err != nil {
+			return err
+		}
+
+		inner := append([]byte("<data>"), parsed.Data.Inner...)
+		return xml.Unmarshal(append(inner, []byte("</data>")...), receiver)
+	}
+
+	parsed := new(Envelope)
+	err := decode(mediaType, body, parsed)
+	if err != nil {
+		return err
+	}
+
+	data, err := encode(mediaType, parsed.Data)
+	if err != nil {
+		return err
+	}
+	return decode(mediaType, data, receiver)
+}
@@ -0,0 +1,144 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/orov-io/BlackBart/env"
+	"github.com/orov-io/BlackBart/requestid"
+	"github.com/sirupsen/logrus"
+)
+
+// HidePolicy decides which error responses hide their errors. Hidden errors
+// are logged and replaced with a HiddenError with a trace ID.
+type HidePolicy string
+
+// The available hide policies.
+const (
+	HideAlways       HidePolicy = "always"
+	HideNever        HidePolicy = "never"
+	HideServerErrors HidePolicy = "5xx"
+)
+
+var hidePolicy = DefaultHidePolicy()
+
+var safeErrors []error
+var safeErrorsMutex sync.RWMutex
+
+// DefaultHidePolicy returns HideAlways on production and HideNever on any
+// other environment.
+func DefaultHidePolicy() HidePolicy {
+	if env.IsProduction() {
+		return HideAlways
+	}
+	return HideNever
+}
+
+// ParseHidePolicy returns the policy of the provided name.
+func ParseHidePolicy(policy string) (HidePolicy, error) {
+	switch HidePolicy(policy) {
+	case HideAlways, HideNever, HideServerErrors:
+		return HidePolicy(policy), nil
+	}
+	return "", NewUnknownHidePolicyError(policy)
+}
+
+// SetHidePolicy sets the policy used to hide the response errors.
+func SetHidePolicy(policy HidePolicy) {
+	hidePolicy = policy
+}
+
+func (p HidePolicy) hides(status int) bool {
+	switch p {
+	case HideAlways:
+		return true
+	case HideServerErrors:
+		return status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// AllowErrors marks the errors as safe to expose, so they are never hidden.
+// Errors wrapping them are matched with errors.Is.
+func AllowErrors(errs ...error) {
+	safeErrorsMutex.Lock()
+	defer safeErrorsMutex.Unlock()
+	safeErrors = append(safeErrors, errs...)
+}
+
+// safeError marks a single error as safe to expose.
+type safeError struct {
+	error
+}
+
+func (e *safeError) Unwrap() error {
+	return e.error
+}
+
+// Safe marks the error as safe to expose, so it is never hidden.
+func Safe(err error) error {
+	return &safeError{err}
+}
+
+// IsSafeError checks if the error can be exposed with any hide policy.
+func IsSafeError(err error) bool {
+	var safe *safeError
+	if errors.As(err, &safe) {
+		return true
+	}
+
+	safeErrorsMutex.RLock()
+	defer safeErrorsMutex.RUnlock()
+	for _, allowed := range safeErrors {
+		if errors.Is(err, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// hideErrors replaces the errors that are not safe to expose with a hidden
+// error, if the hide policy hides the status.
+func (r *Response) hideErrors(status int) {
+	if !hidePolicy.hides(status) {
+		return
+	}
+
+	for i, err := range r.causes {
+		if IsSafeError(err) {
+			continue
+		}
+
+		traceID := r.getTraceID()
+		logger.WithError(err).WithFields(
+			logrus.Fields{
+				"trace_id":    traceID,
+				requestid.Key: requestid.Get(r.ctx),
+			},
+		).Warningf("SERVER ERROR")
+		r.Errors[i] = NewHiddenError(traceID).Error()
+	}
+}
+
+// UnknownHidePolicyError is used when a hide policy does not exist
+type UnknownHidePolicyError struct {
+	Policy string
+}
+
+func (e *UnknownHidePolicyError) Error() string {
+	return fmt.Sprintf("Unknown hide policy: %v. Use one of [always, never, 5xx]", e.Policy)
+}
+
+// NewUnknownHidePolicyError returns a new UnknownHidePolicyError error
+func NewUnknownHidePolicyError(policy string) error {
+	return &UnknownHidePolicyError{policy}
+}
+
+// IsUnknownHidePolicyError checks if the error is a UnknownHidePolicyError
+// error
+func IsUnknownHidePolicyError(err error) bool {
+	_, ok := err.(*UnknownHidePolicyError)
+	return ok
+}
@@ -0,0 +1,102 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setTestHidePolicy(t *testing.T, policy HidePolicy) {
+	t.Helper()
+	previous := hidePolicy
+	SetHidePolicy(policy)
+	t.Cleanup(func() { SetHidePolicy(previous) })
+}
+
+func TestHidePolicyHides(t *testing.T) {
+	tests := []struct {
+		policy HidePolicy
+		status int
+		want   bool
+	}{
+		{HideAlways, http.StatusBadRequest, true},
+		{HideAlways, http.StatusInternalServerError, true},
+		{HideNever, http.StatusBadRequest, false},
+		{HideNever, http.StatusInternalServerError, false},
+		{HideServerErrors, http.StatusBadRequest, false},
+		{HideServerErrors, http.StatusInternalServerError, true},
+		{HideServerErrors, http.StatusServiceUnavailable, true},
+	}
+
+	for _, test := range tests {
+		if got := test.policy.hides(test.status); got != test.want {
+			t.Errorf("%s.hides(%d) = %v, want %v", test.policy, test.status, got, test.want)
+		}
+	}
+}
+
+func TestParseHidePolicy(t *testing.T) {
+	for _, name := range []string{"always", "never", "5xx"} {
+		if policy, err := ParseHidePolicy(name); err != nil || string(policy) != name {
+			t.Errorf("ParseHidePolicy(%q) = %q, %v", name, policy, err)
+		}
+	}
+
+	if _, err := ParseHidePolicy("sometimes"); !IsUnknownHidePolicyError(err) {
+		t.Errorf("ParseHidePolicy(\"sometimes\") error = %v, want UnknownHidePolicyError", err)
+	}
+}
+
+func TestIsSafeError(t *testing.T) {
+	allowed := errors.New("allowed")
+	previous := safeErrors
+	AllowErrors(allowed)
+	t.Cleanup(func() { safeErrors = previous })
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain", errors.New("plain"), false},
+		{"marked", Safe(errors.New("marked")), true},
+		{"wrapping a marked one", fmt.Errorf("context: %w", Safe(errors.New("marked"))), true},
+		{"allowed", allowed, true},
+		{"wrapping an allowed one", fmt.Errorf("context: %w", allowed), true},
+	}
+
+	for _, test := range tests {
+		if got := IsSafeError(test.err); got != test.want {
+			t.Errorf("%s: IsSafeError = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func sendTestError(err error) *httptest.ResponseRecorder {
+	c, recorder := newTestContext("/")
+	SendError(c, err)
+	return recorder
+}
+
+func TestSendErrorHidesByPolicy(t *testing.T) {
+	setTestHidePolicy(t, HideServerErrors)
+	secret := errors.New("secret database error")
+
+	recorder := sendTestError(ErrInternal.WithCause(secret))
+	if strings.Contains(recorder.Body.String(), secret.Error()) {
+		t.Errorf("500 body %s exposes the error", recorder.Body.String())
+	}
+
+	recorder = sendTestError(ErrBadRequest.WithCause(secret))
+	if !strings.Contains(recorder.Body.String(), secret.Error()) {
+		t.Errorf("400 body %s hides the error", recorder.Body.String())
+	}
+
+	recorder = sendTestError(ErrInternal.WithCause(Safe(secret)))
+	if !strings.Contains(recorder.Body.String(), secret.Error()) {
+		t.Errorf("500 body %s hides a safe error", recorder.Body.String())
+	}
+}
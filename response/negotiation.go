@@ -0,0 +1,411 @@
+package response
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v2"
+)
+
+// MIMECSV is the media type of CSV responses.
+const MIMECSV = "text/csv"
+
+// supportedFormats are the media types the response package can encode, by
+// their short names.
+var supportedFormats = map[string]string{
+	"json":    binding.MIMEJSON,
+	"xml":     binding.MIMEXML,
+	"yaml":    binding.MIMEYAML,
+	"msgpack": binding.MIMEMSGPACK,
+	"csv":     MIMECSV,
+}
+
+var formatsOrder = []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEYAML, binding.MIMEMSGPACK, MIMECSV}
+
+var defaultFormat = binding.MIMEJSON
+
+// ErrNotAcceptable is sent when the Accept header of the request has no
+// supported format for the response.
+var ErrNotAcceptable = NewAppError("not_acceptable", http.StatusNotAcceptable, "The requested format is not available")
+
+// SetDefaultFormat sets the format used when the request accepts any format.
+// Accepts a media type or its short name: json, xml, yaml, msgpack or csv.
+func SetDefaultFormat(format string) error {
+	mediaType, ok := getFormat(format)
+	if !ok {
+		return NewUnsupportedFormatError(format)
+	}
+
+	defaultFormat = mediaType
+	return nil
+}
+
+func getFormat(format string) (string, bool) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if mediaType, ok := supportedFormats[format]; ok {
+		return mediaType, true
+	}
+	for _, mediaType := range supportedFormats {
+		if mediaType == format {
+			return mediaType, true
+		}
+	}
+	return "", false
+}
+
+// render encodes the data in the format accepted by the request and sends it
+// with the status. CSV is only offered for slices of structs.
+func (r *Response) render(status int, data interface{}) {
+	format := r.negotiateFormat(data)
+	if format == "" {
+		r.notAcceptable()
+		return
+	}
+
+	body, err := encodeResponse(format, data)
+	if err != nil && format != binding.MIMEJSON {
+		logger.WithError(err).Warnf("Can't encode the response as %s. Falling back to JSON", format)
+		format = binding.MIMEJSON
+		body, err = encodeResponse(format, data)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Can't encode the response")
+		r.sendFallback(http.StatusInternalServerError, ErrInternal, nil)
+		return
+	}
+
+	if wrapsFormat(format) {
+		r.ctx.Header(EnvelopeHeader, "true")
+	}
+	r.ctx.Data(status, contentType(format), body)
+	r.ctx.Abort()
+}
+
+// wrapsFormat reports if the responses in the format are wrapped on the
+// envelope. CSV responses never are.
+func wrapsFormat(format string) bool {
+	return envelope && format != MIMECSV
+}
+
+func encodeResponse(format string, data interface{}) ([]byte, error) {
+	if wrapsFormat(format) {
+		data = wrap(data)
+	}
+	return encode(format, data)
+}
+
+func (r *Response) negotiateFormat(data interface{}) string {
+	offered := make([]string, 0, len(formatsOrder))
+	offered = append(offered, defaultFormat)
+	for _, format := range formatsOrder {
+		if format != defaultFormat {
+			offered = append(offered, format)
+		}
+	}
+
+	if !isCSVEncodable(data) {
+		for i, format := range offered {
+			if format == MIMECSV {
+				offered = append(offered[:i], offered[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return negotiate(r.ctx.GetHeader("Accept"), offered)
+}
+
+// mediaRange models a media range of the Accept header.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiate returns the offered media type with the highest quality on the
+// Accept header, or an empty string if none is acceptable. The quality of an
+// offer is the one of its most specific range: type/subtype, then type/* and
+// then */*. Ties are won by the first offer, so offered[0] is the default.
+func negotiate(header string, offered []string) string {
+	ranges := parseAccept(header)
+	if len(ranges) == 0 {
+		return offered[0]
+	}
+
+	// Browsers navigation requests prefer text/html, that is never offered,
+	// and usually rank XML over */*. They get the default format instead.
+	if acceptsExactly(ranges, "text/html") && getQuality(ranges, offered[0]) > 0 {
+		return offered[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offered {
+		if quality := getQuality(ranges, offer); quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+// parseAccept returns the valid media ranges of the Accept header. Ranges
+// with an invalid quality are ignored.
+func parseAccept(header string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+		if slash := strings.Index(mediaType, "/"); slash <= 0 || slash == len(mediaType)-1 {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			keyValue := strings.SplitN(param, "=", 2)
+			if len(keyValue) != 2 || strings.ToLower(strings.TrimSpace(keyValue[0])) != "q" {
+				continue
+			}
+			var err error
+			quality, err = strconv.ParseFloat(strings.TrimSpace(keyValue[1]), 64)
+			if err != nil || quality < 0 || quality > 1 {
+				quality = -1
+			}
+		}
+		if quality < 0 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// getQuality returns the quality of the most specific range that matches the
+// media type, or 0 if none matches.
+func getQuality(ranges []mediaRange, mediaType string) float64 {
+	mainType := strings.SplitN(mediaType, "/", 2)[0]
+	quality, specificity := 0.0, 0
+	for _, accepted := range ranges {
+		rangeSpecificity := 0
+		switch accepted.mediaType {
+		case mediaType:
+			rangeSpecificity = 3
+		case mainType + "/*":
+			rangeSpecificity = 2
+		case "*/*":
+			rangeSpecificity = 1
+		}
+		if rangeSpecificity > specificity {
+			quality, specificity = accepted.quality, rangeSpecificity
+		}
+	}
+	return quality
+}
+
+func acceptsExactly(ranges []mediaRange, mediaType string) bool {
+	for _, accepted := range ranges {
+		if accepted.mediaType == mediaType && accepted.quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// notAcceptable sends a 406 as JSON, listing the supported formats.
+func (r *Response) notAcceptable() {
+	r.sendFallback(http.StatusNotAcceptable, ErrNotAcceptable, map[string]interface{}{"supported": formatsOrder})
+}
+
+// sendFallback sends the error as JSON, or as a problem, when the response
+// can't be sent in a negotiated format.
+func (r *Response) sendFallback(status int, appErr *AppError, details map[string]interface{}) {
+	r.Code = appErr.Code
+	r.Message = appErr.Message
+	r.Details = details
+	if problemDetails {
+		SendProblem(r.ctx, r.toProblem(status))
+		return
+	}
+
+	if envelope {
+		r.ctx.Header(EnvelopeHeader, "true")
+		r.ctx.JSON(status, wrap(r))
+		r.ctx.Abort()
+		return
+	}
+
+	r.ctx.JSON(status, r)
+	r.ctx.Abort()
+}
+
+func contentType(format string) string {
+	if format == binding.MIMEMSGPACK {
+		return format
+	}
+	return format + "; charset=utf-8"
+}
+
+func encode(format string, data interface{}) ([]byte, error) {
+	switch format {
+	case binding.MIMEXML:
+		return xml.Marshal(data)
+	case binding.MIMEYAML:
+		return yaml.Marshal(data)
+	case binding.MIMEMSGPACK:
+		var body []byte
+		err := codec.NewEncoderBytes(&body, new(codec.MsgpackHandle)).Encode(data)
+		return body, err
+	case MIMECSV:
+		return encodeCSV(data)
+	default:
+		return json.Marshal(data)
+	}
+}
+
+// decode decodes the body by its media type. CSV bodies are not supported.
+func decode(mediaType string, body []byte, receiver interface{}) error {
+	switch mediaType {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return xml.Unmarshal(body, receiver)
+	case binding.MIMEYAML:
+		return yaml.Unmarshal(body, receiver)
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return codec.NewDecoderBytes(body, new(codec.MsgpackHandle)).Decode(receiver)
+	case MIMECSV:
+		return NewUnsupportedFormatError(mediaType)
+	default:
+		return json.Unmarshal(body, receiver)
+	}
+}
+
+func getMediaType(response *http.Response) string {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return binding.MIMEJSON
+	}
+	return mediaType
+}
+
+// csvItems returns the items of the data to encode as CSV rows. Pages are
+// encoded by its items.
+func csvItems(data interface{}) reflect.Value {
+	if page, ok := data.(*Page); ok {
+		data = page.Items
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	return value
+}
+
+func isCSVEncodable(data interface{}) bool {
+	items := csvItems(data)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		return false
+	}
+
+	itemType := items.Type().Elem()
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	return itemType.Kind() == reflect.Struct
+}
+
+// encodeCSV encodes a slice of structs with a header row. Columns are named
+// by the csv tag, the json tag or the field name. Fields tagged with "-" are
+// skipped.
+func encodeCSV(data interface{}) ([]byte, error) {
+	if !isCSVEncodable(data) {
+		return nil, NewUnsupportedFormatError(MIMECSV)
+	}
+
+	items := csvItems(data)
+	itemType := items.Type().Elem()
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+
+	fields := make([]int, 0, itemType.NumField())
+	header := make([]string, 0, itemType.NumField())
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		name := csvColumnName(field)
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		fields = append(fields, i)
+		header = append(header, name)
+	}
+
+	var body bytes.Buffer
+	writer := csv.NewWriter(&body)
+	err := writer.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		for item.Kind() == reflect.Ptr && !item.IsNil() {
+			item = item.Elem()
+		}
+
+		row := make([]string, len(fields))
+		if item.Kind() == reflect.Struct {
+			for column, field := range fields {
+				row[column] = fmt.Sprint(item.Field(field).Interface())
+			}
+		}
+
+		err = writer.Write(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return body.Bytes(), writer.Error()
+}
+
+func csvColumnName(field reflect.StructField) string {
+	for _, tag := range []string{"csv", "json"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// UnsupportedFormatError is used when a format can't be encoded or decoded.
+type UnsupportedFormatError struct {
+	Format string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("Unsupported response format: %v", e.Format)
+}
+
+// NewUnsupportedFormatError returns a new UnsupportedFormatError error
+func NewUnsupportedFormatError(format string) error {
+	return &UnsupportedFormatError{format}
+}
+
+// IsUnsupportedFormatError checks if the error is a UnsupportedFormatError error
+func IsUnsupportedFormatError(err error) bool {
+	_, ok := err.(*UnsupportedFormatError)
+	return ok
+}
@@ -0,0 +1,96 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestNegotiate(t *testing.T) {
+	offered := []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEYAML, binding.MIMEMSGPACK}
+
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"no header", "", binding.MIMEJSON},
+		{"any", "*/*", binding.MIMEJSON},
+		{"exact", "application/xml", binding.MIMEXML},
+		{"case insensitive", "Application/X-YAML", binding.MIMEYAML},
+		{"highest quality", "application/xml;q=0.5, application/x-yaml;q=0.8", binding.MIMEYAML},
+		{"quality with spaces", "application/xml ; q=0.9, application/json; q=0.1", binding.MIMEXML},
+		{"tie keeps the default", "application/xml, application/json", binding.MIMEJSON},
+		{"subtype wildcard", "text/plain, application/*;q=0.5", binding.MIMEJSON},
+		{"exact over wildcard", "application/*;q=0.1, application/x-msgpack", binding.MIMEMSGPACK},
+		{"specific range wins", "application/json;q=0, */*", binding.MIMEXML},
+		{"longer media type", "application/xml-dtd", ""},
+		{"unsupported", "text/plain", ""},
+		{"refused", "*/*;q=0", ""},
+		{"invalid quality", "application/xml;q=abc, application/x-yaml", binding.MIMEYAML},
+		{"only invalid ranges", "nonsense", binding.MIMEJSON},
+		{
+			"browser",
+			"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+			binding.MIMEJSON,
+		},
+	}
+
+	for _, test := range tests {
+		if got := negotiate(test.accept, offered); got != test.want {
+			t.Errorf("%s: negotiate(%q) = %q, want %q", test.name, test.accept, got, test.want)
+		}
+	}
+}
+
+type testCSVRow struct {
+	Name string `json:"name"`
+}
+
+func renderTest(accept string, data interface{}) *httptest.ResponseRecorder {
+	c, recorder := newTestContext("/")
+	c.Request.Header.Set("Accept", accept)
+	SendOK(c, data)
+	return recorder
+}
+
+func TestRenderOffersCSVOnlyForStructSlices(t *testing.T) {
+	recorder := renderTest("text/csv", []testCSVRow{{Name: "foo"}})
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, MIMECSV) {
+		t.Errorf("Content-Type = %q, want %s", got, MIMECSV)
+	}
+	if body := recorder.Body.String(); body != "name\nfoo\n" {
+		t.Errorf("body = %q", body)
+	}
+
+	recorder = renderTest("text/csv", map[string]string{"name": "foo"})
+	if recorder.Code != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotAcceptable)
+	}
+}
+
+func TestRenderFallsBackToJSON(t *testing.T) {
+	// encoding/xml can't encode maps
+	recorder := renderTest("application/xml", map[string]string{"name": "foo"})
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, binding.MIMEJSON) {
+		t.Errorf("Content-Type = %q, want %s", got, binding.MIMEJSON)
+	}
+}
+
+func TestRenderEncodingError(t *testing.T) {
+	recorder := renderTest("application/json", make(chan int))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(recorder.Body.String(), ErrInternal.Code) {
+		t.Errorf("body %s has no %s code", recorder.Body.String(), ErrInternal.Code)
+	}
+}
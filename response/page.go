@@ -0,0 +1,426 @@
+package response
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	limitParam  = "limit"
+	offsetParam = "offset"
+	cursorParam = "cursor"
+	sortParam   = "sort"
+
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+
+	totalCountHeader = "X-Total-Count"
+)
+
+// Pagination errors.
+var (
+	ErrInvalidPage   = NewAppError("invalid_page", http.StatusBadRequest, "The limit, offset or cursor params are not valid")
+	ErrInvalidSort   = NewAppError("invalid_sort", http.StatusBadRequest, "The sort param has not allowed fields")
+	ErrInvalidFilter = NewAppError("invalid_filter", http.StatusBadRequest, "The filter params are not valid")
+)
+
+// PageOptions stores the allowed params of a list endpoint. SortFields and
+// FilterFields map the query param names to the columns they are applied
+// on. Params not on them are rejected or ignored, so only trusted column
+// names end in the queries.
+type PageOptions struct {
+	DefaultLimit int
+	MaxLimit     int
+	DefaultSort  string
+	SortFields   map[string]string
+	FilterFields map[string]string
+}
+
+// NewPageOptions returns a PageOptions struct with the default limits.
+func NewPageOptions() *PageOptions {
+	return &PageOptions{
+		DefaultLimit: defaultPageLimit,
+		MaxLimit:     maxPageLimit,
+		SortFields:   make(map[string]string),
+		FilterFields: make(map[string]string),
+	}
+}
+
+// Sortable allows sorting by the param, applied to the column.
+func (o *PageOptions) Sortable(param string, column string) *PageOptions {
+	o.SortFields[param] = column
+	return o
+}
+
+// Filterable allows filtering by the param, applied to the column.
+func (o *PageOptions) Filterable(param string, column string) *PageOptions {
+	o.FilterFields[param] = column
+	return o
+}
+
+// SortField models a column to sort by.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Filter models the values a column must match.
+type Filter struct {
+	Column string
+	Values []string
+}
+
+// PageParams stores the parsed page, sort and filter params of a request.
+type PageParams struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+	// After are the sort values of the last item of the previous page,
+	// decoded from the cursor. See ParsePageParams.
+	After []interface{}
+}
+
+// ParsePageParams parses the limit, offset, cursor, sort and filter query
+// params of the request. sort is a comma separated list of fields, descending
+// if prefixed by "-", as ?sort=-created_at,name.
+//
+// If there are sort fields, the cursor must be the EncodeCursor of the sort
+// values of the last item of the previous page, as
+// EncodeCursor([]interface{}{last.CreatedAt, last.ID}). They are decoded on
+// the After field and Apply turns them into a keyset condition. Without sort
+// fields, the cursor is opaque: decode it with DecodeCursor.
+func ParsePageParams(c *gin.Context, options *PageOptions) (*PageParams, error) {
+	if options == nil {
+		options = NewPageOptions()
+	}
+
+	params := &PageParams{
+		Limit:  options.DefaultLimit,
+		Cursor: c.Query(cursorParam),
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultPageLimit
+	}
+
+	var err error
+	if limit := c.Query(limitParam); limit != "" {
+		params.Limit, err = strconv.Atoi(limit)
+		if err != nil || params.Limit <= 0 {
+			return nil, ErrInvalidPage.WithDetail("param", limitParam)
+		}
+	}
+	if options.MaxLimit > 0 && params.Limit > options.MaxLimit {
+		params.Limit = options.MaxLimit
+	}
+
+	if offset := c.Query(offsetParam); offset != "" {
+		params.Offset, err = strconv.Atoi(offset)
+		if err != nil || params.Offset < 0 {
+			return nil, ErrInvalidPage.WithDetail("param", offsetParam)
+		}
+	}
+
+	params.Sort, err = parseSort(c.DefaultQuery(sortParam, options.DefaultSort), options.SortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Cursor != "" && len(params.Sort) > 0 {
+		if err = DecodeCursor(params.Cursor, &params.After); err != nil {
+			return nil, err
+		}
+		if len(params.After) != len(params.Sort) {
+			return nil, ErrInvalidPage.WithDetail("param", cursorParam)
+		}
+	}
+
+	params.Filters = parseFilters(c, options.FilterFields)
+	return params, nil
+}
+
+func parseSort(query string, fields map[string]string) ([]SortField, error) {
+	sortFields := make([]SortField, 0)
+	for _, field := range strings.Split(query, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+		column, ok := fields[field]
+		if !ok {
+			return nil, ErrInvalidSort.WithDetail("field", field)
+		}
+		sortFields = append(sortFields, SortField{Column: column, Desc: desc})
+	}
+	return sortFields, nil
+}
+
+func parseFilters(c *gin.Context, fields map[string]string) []Filter {
+	params := make([]string, 0, len(fields))
+	for param := range fields {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	filters := make([]Filter, 0)
+	for _, param := range params {
+		values, ok := c.GetQueryArray(param)
+		if !ok {
+			continue
+		}
+		filters = append(filters, Filter{Column: fields[param], Values: values})
+	}
+	return filters
+}
+
+// OrderBy returns the ORDER BY clause of the sort params, or an empty string.
+func (p *PageParams) OrderBy() string {
+	if len(p.Sort) == 0 {
+		return ""
+	}
+
+	columns := make([]string, 0, len(p.Sort))
+	for _, field := range p.Sort {
+		direction := "ASC"
+		if field.Desc {
+			direction = "DESC"
+		}
+		columns = append(columns, fmt.Sprintf("%s %s", field.Column, direction))
+	}
+	return "ORDER BY " + strings.Join(columns, ", ")
+}
+
+// Where returns the WHERE clause of the filters and of the cursor keyset
+// condition, with ? bindvars, and its args. Filters with many values are
+// matched with IN.
+func (p *PageParams) Where() (string, []interface{}) {
+	if len(p.Filters) == 0 && len(p.After) == 0 {
+		return "", nil
+	}
+
+	conditions := make([]string, 0, len(p.Filters)+1)
+	args := make([]interface{}, 0, len(p.Filters))
+	for _, filter := range p.Filters {
+		if len(filter.Values) == 1 {
+			conditions = append(conditions, filter.Column+" = ?")
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s IN (?%s)",
+				filter.Column, strings.Repeat(", ?", len(filter.Values)-1)))
+		}
+		for _, value := range filter.Values {
+			args = append(args, value)
+		}
+	}
+
+	if len(p.After) > 0 {
+		keyset, keysetArgs := p.keyset()
+		conditions = append(conditions, keyset)
+		args = append(args, keysetArgs...)
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// keyset returns the condition that selects the rows after the After values
+// on the sort order, as ((a > ?) OR (a = ? AND b < ?)) for ?sort=a,-b. The
+// sort must end with an unique column for the pages to not skip rows.
+func (p *PageParams) keyset() (string, []interface{}) {
+	alternatives := make([]string, 0, len(p.Sort))
+	args := make([]interface{}, 0)
+	for i, field := range p.Sort {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, p.Sort[j].Column+" = ?")
+			args = append(args, p.After[j])
+		}
+
+		operator := ">"
+		if field.Desc {
+			operator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s ?", field.Column, operator))
+		args = append(args, p.After[i])
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// Apply appends the WHERE, ORDER BY, LIMIT and OFFSET clauses to the query.
+// On cursor based pages, the WHERE clause selects the rows after the cursor
+// and there is no OFFSET. The query uses ? bindvars; use sqlx Rebind to run it
+// on postgres:
+//
+//	query, args := params.Apply("SELECT * FROM users")
+//	err := db.Select(&users, db.Rebind(query), args...)
+func (p *PageParams) Apply(query string) (string, []interface{}) {
+	where, args := p.Where()
+	clauses := []string{query}
+	if where != "" {
+		clauses = append(clauses, where)
+	}
+	if orderBy := p.OrderBy(); orderBy != "" {
+		clauses = append(clauses, orderBy)
+	}
+	clauses = append(clauses, "LIMIT ?")
+	args = append(args, p.Limit)
+	if p.Cursor == "" {
+		clauses = append(clauses, "OFFSET ?")
+		args = append(args, p.Offset)
+	}
+	return strings.Join(clauses, " "), args
+}
+
+// EncodeCursor encodes the value as an opaque cursor.
+func EncodeCursor(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes an opaque cursor into the receiver.
+func DecodeCursor(cursor string, receiver interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidPage.WithCause(err).WithDetail("param", cursorParam)
+	}
+
+	err = json.Unmarshal(data, receiver)
+	if err != nil {
+		return ErrInvalidPage.WithCause(err).WithDetail("param", cursorParam)
+	}
+	return nil
+}
+
+// PageInfo models the page of a list. Fill Offset for offset based pages, or
+// NextCursor and PrevCursor for cursor based ones. Total is only sent if it
+// is known. Use WithTotal to set it.
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// NewPageInfo returns the PageInfo of the params.
+func NewPageInfo(params *PageParams) *PageInfo {
+	return &PageInfo{
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+}
+
+// WithTotal sets the total count of items.
+func (p *PageInfo) WithTotal(total int64) *PageInfo {
+	p.Total = &total
+	return p
+}
+
+// WithCursors sets the cursors of the next and previous pages. Leave them
+// empty if there are no more pages.
+func (p *PageInfo) WithCursors(next string, prev string) *PageInfo {
+	p.NextCursor = next
+	p.PrevCursor = prev
+	return p
+}
+
+func (p *PageInfo) isCursorBased() bool {
+	return p.NextCursor != "" || p.PrevCursor != ""
+}
+
+// Page models the response of a list.
+type Page struct {
+	Items interface{} `json:"items"`
+	Page  *PageInfo   `json:"page"`
+}
+
+// SendPage sends the items of a page with a 200 http code. The RFC 8288 Link
+// header links the next, prev, first and last pages, and X-Total-Count the
+// total count, if known.
+func SendPage(c *gin.Context, items interface{}, info *PageInfo) {
+	if info.Total != nil {
+		c.Header(totalCountHeader, strconv.FormatInt(*info.Total, 10))
+	}
+	if links := getPageLinks(c, info, pageLength(items)); len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+
+	SendOK(c, &Page{Items: items, Page: info})
+}
+
+func getPageLinks(c *gin.Context, info *PageInfo, length int) []string {
+	links := make([]string, 0, 4)
+	link := func(rel string, params map[string]string) {
+		url := *c.Request.URL
+		query := url.Query()
+		if _, ok := params[cursorParam]; ok {
+			query.Del(offsetParam)
+		}
+		for key, value := range params {
+			query.Set(key, value)
+		}
+		url.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", url.RequestURI(), rel))
+	}
+
+	limit := strconv.Itoa(info.Limit)
+	if info.isCursorBased() {
+		if info.NextCursor != "" {
+			link("next", map[string]string{cursorParam: info.NextCursor, limitParam: limit})
+		}
+		if info.PrevCursor != "" {
+			link("prev", map[string]string{cursorParam: info.PrevCursor, limitParam: limit})
+		}
+		return links
+	}
+
+	if info.Limit <= 0 {
+		return links
+	}
+
+	hasNext := length >= info.Limit
+	if info.Total != nil {
+		hasNext = int64(info.Offset+info.Limit) < *info.Total
+	}
+	if hasNext {
+		link("next", map[string]string{offsetParam: strconv.Itoa(info.Offset + info.Limit), limitParam: limit})
+	}
+	if info.Offset > 0 {
+		prev := info.Offset - info.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link("prev", map[string]string{offsetParam: strconv.Itoa(prev), limitParam: limit})
+		link("first", map[string]string{offsetParam: "0", limitParam: limit})
+	}
+	if info.Total != nil && *info.Total > 0 {
+		last := (*info.Total - 1) / int64(info.Limit) * int64(info.Limit)
+		link("last", map[string]string{offsetParam: strconv.FormatInt(last, 10), limitParam: limit})
+	}
+	return links
+}
+
+// pageLength returns the length of the items if it is a slice, or -1.
+func pageLength(items interface{}) int {
+	value := reflect.ValueOf(items)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return -1
+	}
+	return value.Len()
+}
@@ -0,0 +1,140 @@
+package response
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testPageOptions() *PageOptions {
+	return NewPageOptions().
+		Sortable("created_at", "created_at").
+		Sortable("id", "id").
+		Filterable("status", "status")
+}
+
+func TestParsePageParamsLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		options *PageOptions
+		want    int
+		wantErr bool
+	}{
+		{"default", "/", NewPageOptions(), defaultPageLimit, false},
+		{"nil options", "/", nil, defaultPageLimit, false},
+		{"struct literal options", "/", &PageOptions{}, defaultPageLimit, false},
+		{"requested", "/?limit=5", NewPageOptions(), 5, false},
+		{"clamped to max", "/?limit=1000", NewPageOptions(), maxPageLimit, false},
+		{"zero", "/?limit=0", NewPageOptions(), 0, true},
+		{"not a number", "/?limit=abc", NewPageOptions(), 0, true},
+	}
+
+	for _, test := range tests {
+		c, _ := newTestContext(test.target)
+		params, err := ParsePageParams(c, test.options)
+		if test.wantErr {
+			if !errors.Is(err, ErrInvalidPage) {
+				t.Errorf("%s: err = %v, want ErrInvalidPage", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if params.Limit != test.want {
+			t.Errorf("%s: limit = %d, want %d", test.name, params.Limit, test.want)
+		}
+	}
+}
+
+func TestParsePageParamsRejectsUnknownSort(t *testing.T) {
+	c, _ := newTestContext("/?sort=password")
+	if _, err := ParsePageParams(c, testPageOptions()); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("err = %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestApplyOffset(t *testing.T) {
+	c, _ := newTestContext("/?sort=-created_at&status=active&status=new&limit=10&offset=20&unknown=1")
+	params, err := ParsePageParams(c, testPageOptions())
+	if err != nil {
+		t.Fatalf("ParsePageParams: %v", err)
+	}
+
+	query, args := params.Apply("SELECT * FROM users")
+	wantQuery := "SELECT * FROM users WHERE status IN (?, ?) ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	if query != wantQuery {
+		t.Errorf("query = %q, want %q", query, wantQuery)
+	}
+	wantArgs := []interface{}{"active", "new", 10, 20}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	cursor, err := EncodeCursor([]interface{}{"2020-01-01T00:00:00Z", 42})
+	if err != nil {
+		t.Fatalf("EncodeCursor: %v", err)
+	}
+
+	c, _ := newTestContext("/?sort=-created_at,id&status=active&limit=10&cursor=" + cursor)
+	params, err := ParsePageParams(c, testPageOptions())
+	if err != nil {
+		t.Fatalf("ParsePageParams: %v", err)
+	}
+
+	query, args := params.Apply("SELECT * FROM users")
+	wantQuery := "SELECT * FROM users WHERE status = ? AND ((created_at < ?) OR (created_at = ? AND id > ?)) " +
+		"ORDER BY created_at DESC, id ASC LIMIT ?"
+	if query != wantQuery {
+		t.Errorf("query = %q, want %q", query, wantQuery)
+	}
+	wantArgs := []interface{}{"active", "2020-01-01T00:00:00Z", "2020-01-01T00:00:00Z", float64(42), 10}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestParsePageParamsRejectsMismatchedCursor(t *testing.T) {
+	cursor, _ := EncodeCursor([]interface{}{42})
+	c, _ := newTestContext("/?sort=-created_at,id&cursor=" + cursor)
+	if _, err := ParsePageParams(c, testPageOptions()); !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("err = %v, want ErrInvalidPage", err)
+	}
+}
+
+func TestSendPageWithZeroTotal(t *testing.T) {
+	c, recorder := newTestContext("/users")
+	SendPage(c, []string{}, NewPageInfo(&PageParams{Limit: 10}).WithTotal(0))
+
+	if got := recorder.Header().Get(totalCountHeader); got != "0" {
+		t.Errorf("%s = %q, want \"0\"", totalCountHeader, got)
+	}
+	if !strings.Contains(recorder.Body.String(), `"total":0`) {
+		t.Errorf("body %s has no zero total", recorder.Body.String())
+	}
+	if links := recorder.Header().Get("Link"); links != "" {
+		t.Errorf("Link = %q, want no links", links)
+	}
+}
+
+func TestSendPageLinks(t *testing.T) {
+	c, recorder := newTestContext("/users?offset=10&limit=10")
+	SendPage(c, make([]string, 10), NewPageInfo(&PageParams{Limit: 10, Offset: 10}).WithTotal(35))
+
+	links := recorder.Header().Get("Link")
+	for _, want := range []string{
+		`</users?limit=10&offset=20>; rel="next"`,
+		`</users?limit=10&offset=0>; rel="prev"`,
+		`</users?limit=10&offset=0>; rel="first"`,
+		`</users?limit=10&offset=30>; rel="last"`,
+	} {
+		if !strings.Contains(links, want) {
+			t.Errorf("Link = %q, missing %q", links, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+const (
+	defaultProblemType = "about:blank"
+	errorsExtension    = "errors"
+	traceIDExtension   = "trace_id"
+)
+
+var problemDetails bool
+
+// SetProblemDetails enables or disables the RFC 7807 problem details mode.
+// When enabled, error responses are sent as application/problem+json instead
+// of the {message, errors} Response.
+func SetProblemDetails(enabled bool) {
+	problemDetails = enabled
+}
+
+// Problem models a RFC 7807 problem details object. Extensions are written as
+// top level members of the JSON object.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem returns a problem of the default type with the standard title
+// of the status.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   defaultProblemType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With adds an extension member to the problem.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Errors returns the errors extension member, if any.
+func (p *Problem) Errors() []string {
+	raw, ok := p.Extensions[errorsExtension].([]interface{})
+	if !ok {
+		errors, _ := p.Extensions[errorsExtension].([]string)
+		return errors
+	}
+
+	errors := make([]string, 0, len(raw))
+	for _, err := range raw {
+		if message, ok := err.(string); ok {
+			errors = append(errors, message)
+		}
+	}
+	return errors
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON writes the problem members and its extensions in a single
+// object.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = defaultProblemType
+	}
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON reads the problem members. Unknown members are stored as
+// extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]interface{}
+	err := json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	p.Type, _ = members["type"].(string)
+	p.Title, _ = members["title"].(string)
+	p.Detail, _ = members["detail"].(string)
+	p.Instance, _ = members["instance"].(string)
+	if status, ok := members["status"].(float64); ok {
+		p.Status = int(status)
+	}
+
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+
+	return nil
+}
+
+// SendProblem sends the problem with its status and the problem+json content
+// type.
+func SendProblem(c *gin.Context, problem *Problem) {
+	status := problem.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if problem.Instance == "" {
+		problem.Instance = c.Request.URL.RequestURI()
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(status, problem)
+	c.Abort()
+}
+
+// toProblem translates the response to a problem with the provided status.
+func (r *Response) toProblem(status int) *Problem {
+	problem := NewProblem(status, r.Message)
+	if r.Code != "" {
+		problem.With(codeExtension, r.Code)
+	}
+	if len(r.Details) > 0 {
+		problem.With(detailsExtension, r.Details)
+	}
+	if len(r.Errors) > 0 {
+		problem.With(errorsExtension, r.Errors)
+	}
+	if r.traceID != "" {
+		problem.With(traceIDExtension, r.traceID)
+	}
+	return problem
+}
+
+// sendError writes the response as an error with the provided status, in the
+// configured error format.
+func (r *Response) sendError(status int) {
+	r.hideErrors(status)
+	if problemDetails {
+		SendProblem(r.ctx, r.toProblem(status))
+		return
+	}
+
+	r.render(status, r)
+}
+
+func isProblem(response *http.Response) bool {
+	return strings.HasPrefix(response.Header.Get("Content-Type"), ProblemContentType)
+}
+
+func parseProblem(body []byte) (*Response, error) {
+	problem := new(Problem)
+	err := json.Unmarshal(body, problem)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Response{
+		Message: problem.Error(),
+		Errors:  problem.Errors(),
+		problem: problem,
+	}
+	r.Code, _ = problem.Extensions[codeExtension].(string)
+	r.Details, _ = problem.Extensions[detailsExtension].(map[string]interface{})
+	return r, nil
+}
@@ -1,23 +1,18 @@
 package response
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"reflect"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
+	"github.com/orov-io/BlackBart/requestid"
 	"github.com/sirupsen/logrus"
 )
 
-const (
-	production = "prod"
-	envKey     = "ENV"
-)
-
 // Logger is the needed interface to log entries by the response package
 type Logger interface {
 	WithError(err error) *logrus.Entry
@@ -34,12 +29,33 @@ func SetLogger(theLogger Logger) {
 	logger = theLogger
 }
 
+// ServerErrorHook is called with each error sent on a 5xx response, before
+// it is hidden. traceID is the ID sent to the client on hidden errors.
+type ServerErrorHook func(c *gin.Context, err error, status int, traceID string)
+
+var serverErrorHook ServerErrorHook
+
+// SetServerErrorHook sets the hook called with server errors. Use it to send
+// them to an error reporter.
+func SetServerErrorHook(hook ServerErrorHook) {
+	serverErrorHook = hook
+}
+
 // Response models standard response
 type Response struct {
-	Message string   `json:"message,omitempty"`
-	Errors  []string `json:"errors,omitempty"`
-	data    interface{}
-	ctx     *gin.Context
+	Code      string                 `json:"code,omitempty" xml:"code,omitempty" yaml:"code,omitempty"`
+	Message   string                 `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	Errors    []string               `json:"errors,omitempty" xml:"errors>error,omitempty" yaml:"errors,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty" xml:"-" yaml:"details,omitempty"`
+	data      interface{}
+	ctx       *gin.Context
+	causes    []error
+	traceID   string
+	problem   *Problem
+	body      []byte
+	mediaType string
+	enveloped bool
+	meta      interface{}
 }
 
 // NewResponse returns a response struct with a context attached
@@ -49,12 +65,24 @@ func newResponse(c *gin.Context) *Response {
 	return r
 }
 
+// Problem returns the problem details of a parsed problem+json response.
+func (r *Response) Problem() *Problem {
+	return r.problem
+}
+
+// Meta returns the meta field of a parsed response on envelope mode, as the
+// page info.
+func (r *Response) Meta() interface{} {
+	return r.meta
+}
+
 // AddData adds objects to the data response field
 func (r *Response) addData(data interface{}) {
 	r.data = data
 }
 
-// AddError adds objects to the errors response field
+// AddError adds objects to the errors response field. They are hidden on
+// send, following the hide policy.
 func (r *Response) addError(errors ...error) {
 	if r.Errors == nil {
 		r.Errors = make([]string, 0)
@@ -64,51 +92,62 @@ func (r *Response) addError(errors ...error) {
 		if err == nil {
 			err = fmt.Errorf("Unknown error")
 		}
-
-		if os.Getenv(envKey) == production {
-			traceID := uuid.New().String()
-			logger.WithError(err).WithFields(
-				logrus.Fields{
-					"trace_id": traceID,
-				},
-			).Warningf("SERVER ERROR")
-			err = NewHiddenError(traceID)
-		}
+		r.causes = append(r.causes, err)
 		r.Errors = append(r.Errors, err.Error())
 	}
 }
 
+// getTraceID reuses the request ID, so hidden errors can be found on logs with
+// the ID the client already knows. A new one is generated if there is none.
+func (r *Response) getTraceID() string {
+	if r.traceID != "" {
+		return r.traceID
+	}
+
+	r.traceID = requestid.Get(r.ctx)
+	if r.traceID == "" {
+		r.traceID = uuid.New().String()
+	}
+	return r.traceID
+}
+
+// reportServerErrors sends the response errors to the server error hook.
+func (r *Response) reportServerErrors(status int) {
+	if serverErrorHook == nil {
+		return
+	}
+
+	for _, err := range r.causes {
+		serverErrorHook(r.ctx, err, status, r.getTraceID())
+	}
+}
+
 // Unauthorized sends a 401 code to the client and ask for re-loggin
 func (r *Response) unauthorized() {
 	r.Message = "You are no logged-in. Please, loggin"
-	r.ctx.JSON(http.StatusUnauthorized, r)
-	r.ctx.Abort()
+	r.sendError(http.StatusUnauthorized)
 }
 
 func (r *Response) forbidden() {
 	r.Message = "User has no enough permissions"
-	r.ctx.JSON(http.StatusForbidden, r)
-	r.ctx.Abort()
+	r.sendError(http.StatusForbidden)
 }
 
 func (r *Response) badRequest() {
-	r.ctx.JSON(http.StatusBadRequest, r)
-	r.ctx.Abort()
+	r.sendError(http.StatusBadRequest)
 }
 
 func (r *Response) notFound() {
-	r.ctx.String(http.StatusNotFound, "404 page not found")
-	r.ctx.Abort()
+	r.sendStatus(http.StatusNotFound)
 }
 
 func (r *Response) internalError() {
-	r.ctx.JSON(http.StatusInternalServerError, r)
-	r.ctx.Abort()
+	r.reportServerErrors(http.StatusInternalServerError)
+	r.sendError(http.StatusInternalServerError)
 }
 
 func (r *Response) ok() {
-	r.ctx.JSON(http.StatusOK, r.data)
-	r.ctx.Abort()
+	r.render(http.StatusOK, r.data)
 }
 
 // here, we expose the response catalog of the server
@@ -127,9 +166,11 @@ func SendBadRequest(c *gin.Context, errors ...error) {
 	r.badRequest()
 }
 
-// SendNotFoundRequest returns a 404 code with standard message in body
-func SendNotFoundRequest(c *gin.Context) {
+// SendNotFoundRequest returns a 404 code with standard message and the errors
+// info in body
+func SendNotFoundRequest(c *gin.Context, errors ...error) {
 	r := newResponse(c)
+	r.addError(errors...)
 	r.notFound()
 }
 
@@ -165,7 +206,7 @@ func SendCreated(c *gin.Context, location string, data ...interface{}) {
 		response = data
 	}
 
-	c.JSON(http.StatusCreated, response)
+	newResponse(c).render(http.StatusCreated, response)
 }
 
 // SendNoContent returns a 204 http code with no body.
@@ -187,14 +228,20 @@ func SendForbidden(c *gin.Context, errors ...error) {
 	r.forbidden()
 }
 
-// Parse parses the body of a http.response to a Response struct
+// Parse parses the body of a http.response to a Response struct. Error
+// responses in the application/problem+json format are parsed too, and their
+// details are available on the Problem method of the response and the error.
 func Parse(response *http.Response) (*Response, error) {
 	if !isValidResponse(response) {
 		r, err := parseError(response)
 		if err != nil {
 			return r, NewError(response.StatusCode, "Unknown response format")
 		}
-		return r, NewError(response.StatusCode, r.Message)
+		return r, &Error{
+			message: r.Message,
+			code:    response.StatusCode,
+			problem: r.problem,
+		}
 	}
 
 	return parseValid(response)
@@ -204,6 +251,7 @@ func Parse(response *http.Response) (*Response, error) {
 type Error struct {
 	message string
 	code    int
+	problem *Problem
 }
 
 // NewError returns a new Error
@@ -223,6 +271,11 @@ func (e *Error) Code() int {
 	return e.code
 }
 
+// Problem returns the problem details sent by the server, if any.
+func (e *Error) Problem() *Problem {
+	return e.problem
+}
+
 // IsError checks if the error is a ResponseError error
 func IsError(err error) bool {
 	_, ok := err.(*Error)
@@ -235,8 +288,16 @@ func parseError(response *http.Response) (r *Response, err error) {
 		return nil, err
 	}
 
+	if isProblem(response) {
+		return parseProblem(body)
+	}
+
+	if isEnveloped(response) {
+		return parseErrorEnvelope(getMediaType(response), body)
+	}
+
 	r = new(Response)
-	err = json.Unmarshal(body, r)
+	err = decode(getMediaType(response), body, r)
 	if err != nil {
 		return nil, err
 	}
@@ -250,9 +311,25 @@ func parseValid(response *http.Response) (r *Response, err error) {
 		return
 	}
 
+	r.body = body
+	r.mediaType = getMediaType(response)
+	r.enveloped = isEnveloped(response)
+	if len(body) == 0 || r.mediaType == binding.MIMEXML || r.mediaType == binding.MIMEXML2 || r.mediaType == MIMECSV {
+		// These formats can only be decoded into a receiver, on ParseTo
+		return
+	}
+
+	if r.enveloped {
+		parsed := new(Envelope)
+		err = decode(r.mediaType, body, parsed)
+		r.data = parsed.Data
+		r.meta = parsed.Meta
+		return
+	}
+
 	var data interface{}
 
-	err = json.Unmarshal(body, &data)
+	err = decode(r.mediaType, body, &data)
 	if err != nil {
 		return
 	}
@@ -263,7 +340,8 @@ func parseValid(response *http.Response) (r *Response, err error) {
 }
 
 // ParseTo parses the body response and unmarshal content of the data
-// field into the receiver
+// field into the receiver. Responses on envelope mode are unwrapped, so the
+// receiver gets the same data on both modes.
 func ParseTo(response *http.Response, receiver interface{}) error {
 	if !isAPointer(receiver) {
 		return NewNotAPointerError()
@@ -274,11 +352,15 @@ func ParseTo(response *http.Response, receiver interface{}) error {
 		return err
 	}
 
-	ResponseBytes, err := json.Marshal(ParsedResponse.data)
-	if err != nil {
-		return fmt.Errorf("Error: %v\nCan't marshal response data: %v", err, ParsedResponse)
+	if len(ParsedResponse.body) == 0 {
+		return nil
+	}
+
+	if ParsedResponse.enveloped {
+		err = unwrap(ParsedResponse.mediaType, ParsedResponse.body, receiver)
+	} else {
+		err = decode(ParsedResponse.mediaType, ParsedResponse.body, receiver)
 	}
-	err = json.Unmarshal(ResponseBytes, receiver)
 	if err != nil {
 		return fmt.Errorf("Error: %v\nCan't unmarshal response data: %v", err, ParsedResponse)
 	}
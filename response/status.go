@@ -0,0 +1,133 @@
+package response
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusMessages are the standard messages of the error statuses.
+var statusMessages = map[int]string{
+	http.StatusNotFound:              "The resource does not exist",
+	http.StatusConflict:              "The request is in conflict with the resource state",
+	http.StatusGone:                  "The resource is no longer available",
+	http.StatusPreconditionFailed:    "The resource does not match the request preconditions",
+	http.StatusRequestEntityTooLarge: "The request body is too large",
+	http.StatusUnsupportedMediaType:  "The request content type is not supported",
+	http.StatusUnprocessableEntity:   "The request can't be processed",
+	http.StatusTooManyRequests:       "Too many requests. Please, retry later",
+	http.StatusNotImplemented:        "The functionality is not implemented",
+	http.StatusBadGateway:            "An upstream service sent an invalid response",
+	http.StatusServiceUnavailable:    "The service is temporarily unavailable",
+	http.StatusGatewayTimeout:        "An upstream service did not respond in time",
+}
+
+// AcceptedResponse models the body of a 202 response.
+type AcceptedResponse struct {
+	StatusURL string `json:"status_url" xml:"status_url" yaml:"status_url"`
+}
+
+// sendStatus sends the errors with the standard message of the status.
+func (r *Response) sendStatus(status int) {
+	r.Message = statusMessages[status]
+	if status >= http.StatusInternalServerError {
+		r.reportServerErrors(status)
+	}
+	r.sendError(status)
+}
+
+func sendStatus(c *gin.Context, status int, errors []error) {
+	r := newResponse(c)
+	r.addError(errors...)
+	r.sendStatus(status)
+}
+
+// setRetryAfter sets the Retry-After header in seconds, if any.
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// SendAccepted returns a 202 http code with the URL to check the status of
+// the request on the Location header. The data is sent if any; otherwise,
+// the body has the status URL.
+func SendAccepted(c *gin.Context, statusURL string, data ...interface{}) {
+	c.Header("Location", statusURL)
+
+	var response interface{} = &AcceptedResponse{StatusURL: statusURL}
+	switch len(data) {
+	case 0:
+	case 1:
+		response = data[0]
+	default:
+		response = data
+	}
+
+	newResponse(c).render(http.StatusAccepted, response)
+}
+
+// SendConflict returns a 409 code with the errors info to the client
+func SendConflict(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusConflict, errors)
+}
+
+// SendGone returns a 410 code with the errors info to the client
+func SendGone(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusGone, errors)
+}
+
+// SendPreconditionFailed returns a 412 code with the errors info to the client
+func SendPreconditionFailed(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusPreconditionFailed, errors)
+}
+
+// SendPayloadTooLarge returns a 413 code with the errors info to the client
+func SendPayloadTooLarge(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusRequestEntityTooLarge, errors)
+}
+
+// SendUnsupportedMediaType returns a 415 code with the errors info to the
+// client
+func SendUnsupportedMediaType(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusUnsupportedMediaType, errors)
+}
+
+// SendUnprocessableEntity returns a 422 code with the errors info to the
+// client
+func SendUnprocessableEntity(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusUnprocessableEntity, errors)
+}
+
+// SendTooManyRequests returns a 429 code with the Retry-After header, if
+// retryAfter is positive, and the errors info
+func SendTooManyRequests(c *gin.Context, retryAfter time.Duration, errors ...error) {
+	setRetryAfter(c, retryAfter)
+	sendStatus(c, http.StatusTooManyRequests, errors)
+}
+
+// SendNotImplemented sends a standard 501 response
+func SendNotImplemented(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusNotImplemented, errors)
+}
+
+// SendBadGateway sends a standard 502 response
+func SendBadGateway(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusBadGateway, errors)
+}
+
+// SendServiceUnavailable sends a standard 503 response with the Retry-After
+// header, if retryAfter is positive
+func SendServiceUnavailable(c *gin.Context, retryAfter time.Duration, errors ...error) {
+	setRetryAfter(c, retryAfter)
+	sendStatus(c, http.StatusServiceUnavailable, errors)
+}
+
+// SendGatewayTimeout sends a standard 504 response
+func SendGatewayTimeout(c *gin.Context, errors ...error) {
+	sendStatus(c, http.StatusGatewayTimeout, errors)
+}
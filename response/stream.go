@@ -0,0 +1,293 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Streaming media types.
+const (
+	MIMEEventStream = "text/event-stream"
+	MIMENDJSON      = "application/x-ndjson"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	lastEventIDParam  = "lastEventId"
+	ndjsonFlushEvery  = 100
+)
+
+// Event models a Server-Sent Event. Data is sent as is if it is a string or
+// []byte, and as JSON otherwise. Retry, if positive, updates the reconnection
+// time of the client.
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+// SSEStream sends Server-Sent Events to the client. It is safe for
+// concurrent use.
+type SSEStream struct {
+	ctx   *gin.Context
+	mutex sync.Mutex
+}
+
+// SSE starts a Server-Sent Events stream on the request:
+//
+//	stream := response.SSE(c)
+//	events := subscribe(stream.LastEventID())
+//	err := stream.Run(events, 15*time.Second)
+func SSE(c *gin.Context) *SSEStream {
+	header := c.Writer.Header()
+	header.Set("Content-Type", MIMEEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	return &SSEStream{ctx: c}
+}
+
+// LastEventID returns the ID of the last event received by a reconnecting
+// client, from the Last-Event-ID header or the lastEventId query param. Use
+// it to resume the stream.
+func (s *SSEStream) LastEventID() string {
+	if id := s.ctx.GetHeader(lastEventIDHeader); id != "" {
+		return id
+	}
+	return s.ctx.Query(lastEventIDParam)
+}
+
+// Done is closed when the client disconnects.
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.ctx.Request.Context().Done()
+}
+
+// Send sends the event and flushes it to the client.
+func (s *SSEStream) Send(event Event) error {
+	var message bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&message, "id: %s\n", singleLine(event.ID))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&message, "event: %s\n", singleLine(event.Event))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&message, "retry: %d\n", event.Retry.Milliseconds())
+	}
+
+	data, err := eventData(event.Data)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&message, "data: %s\n", line)
+	}
+	message.WriteString("\n")
+
+	return s.write(message.Bytes())
+}
+
+// Retry sets the reconnection time of the client.
+func (s *SSEStream) Retry(retry time.Duration) error {
+	return s.write([]byte("retry: " + strconv.FormatInt(retry.Milliseconds(), 10) + "\n\n"))
+}
+
+// Heartbeat sends a comment, ignored by clients, to keep the connection
+// alive through proxies.
+func (s *SSEStream) Heartbeat() error {
+	return s.write([]byte(":\n\n"))
+}
+
+// Run sends the events until the channel is closed or the client
+// disconnects, with a heartbeat every period, if positive. Returns the write
+// error, if any, or the context error if the client disconnects.
+func (s *SSEStream) Run(events <-chan Event, heartbeat time.Duration) error {
+	var ticks <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.Done():
+			return s.ctx.Request.Context().Err()
+
+		case <-ticks:
+			err := s.Heartbeat()
+			if err != nil {
+				return err
+			}
+
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			err := s.Send(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *SSEStream) write(message []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.Done():
+		return s.ctx.Request.Context().Err()
+	default:
+	}
+
+	_, err := s.ctx.Writer.Write(message)
+	if err != nil {
+		return err
+	}
+	s.ctx.Writer.Flush()
+	return nil
+}
+
+func eventData(data interface{}) (string, error) {
+	switch data := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return data, nil
+	case []byte:
+		return string(data), nil
+	}
+
+	encoded, err := json.Marshal(data)
+	return string(encoded), err
+}
+
+// singleLine removes the line breaks, not allowed on the id and event fields.
+func singleLine(field string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(field)
+}
+
+// NDJSONStream sends newline delimited JSON items to the client, flushing
+// them progressively.
+type NDJSONStream struct {
+	ctx     *gin.Context
+	encoder *json.Encoder
+	pending int
+}
+
+// NDJSON starts a newline delimited JSON stream on the request. Write the
+// items as they are read, so large exports are never buffered:
+//
+//	stream := response.NDJSON(c)
+//	for rows.Next() {
+//		...
+//		err = stream.Write(item)
+//	}
+//	stream.Flush()
+func NDJSON(c *gin.Context) *NDJSONStream {
+	c.Header("Content-Type", MIMENDJSON)
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	return &NDJSONStream{
+		ctx:     c,
+		encoder: json.NewEncoder(c.Writer),
+	}
+}
+
+// Write sends the item as a JSON line. Items are flushed in batches. Returns
+// the context error if the client disconnects.
+func (s *NDJSONStream) Write(item interface{}) error {
+	err := s.ctx.Request.Context().Err()
+	if err != nil {
+		return err
+	}
+
+	err = s.encoder.Encode(item)
+	if err != nil {
+		return err
+	}
+
+	s.pending++
+	if s.pending >= ndjsonFlushEvery {
+		s.Flush()
+	}
+	return nil
+}
+
+// Flush sends the pending items to the client.
+func (s *NDJSONStream) Flush() {
+	s.ctx.Writer.Flush()
+	s.pending = 0
+}
+
+// SendNDJSON streams the items of a slice or a channel as newline delimited
+// JSON. Channels are streamed as they are received, until closed or the
+// client disconnects.
+func SendNDJSON(c *gin.Context, items interface{}) error {
+	value := reflect.ValueOf(items)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Chan:
+		if value.Type().ChanDir()&reflect.RecvDir == 0 {
+			return NewUnsupportedFormatError(MIMENDJSON)
+		}
+	default:
+		return NewUnsupportedFormatError(MIMENDJSON)
+	}
+
+	stream := NDJSON(c)
+	defer stream.Flush()
+
+	if value.Kind() == reflect.Chan {
+		return stream.writeChan(value)
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		err := stream.Write(value.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeChan writes the items received from the channel until it is closed.
+// The receive is selected with the request context, so a disconnected client
+// doesn't leave the handler blocked on an idle channel.
+func (s *NDJSONStream) writeChan(items reflect.Value) error {
+	ctx := s.ctx.Request.Context()
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: items},
+	}
+
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return ctx.Err()
+		}
+		if !ok {
+			return nil
+		}
+		err := s.Write(item.Interface())
+		if err != nil {
+			return err
+		}
+	}
+}
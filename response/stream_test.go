@@ -0,0 +1,49 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSendNDJSONChan(t *testing.T) {
+	c, recorder := newTestContext("/")
+	items := make(chan int, 2)
+	items <- 1
+	items <- 2
+	close(items)
+
+	if err := SendNDJSON(c, items); err != nil {
+		t.Fatalf("SendNDJSON: %v", err)
+	}
+	if body := recorder.Body.String(); body != "1\n2\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSendNDJSONStopsOnDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newTestContext("/")
+	c.Request = c.Request.WithContext(ctx)
+
+	done := make(chan error, 1)
+	go func() { done <- SendNDJSON(c, make(chan int)) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendNDJSON kept waiting on the channel after the disconnect")
+	}
+}
+
+func TestSendNDJSONRejectsSendOnlyChan(t *testing.T) {
+	c, _ := newTestContext("/")
+	if err := SendNDJSON(c, make(chan<- int)); !IsUnsupportedFormatError(err) {
+		t.Errorf("err = %v, want UnsupportedFormatError", err)
+	}
+}
@@ -0,0 +1,357 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	formTag         = "form"
+	uriTag          = "uri"
+	defaultLanguage = "en"
+	typeRule        = "type"
+	fieldsDetail    = "fields"
+	fieldHolder     = "{field}"
+	paramHolder     = "{param}"
+)
+
+// ErrValidation is sent when the request does not pass the validation.
+var ErrValidation = NewAppError("validation_failed", http.StatusBadRequest, "The request has invalid fields")
+
+// FieldError models a field that does not pass the validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// validationMessages stores the message templates of the validation rules by
+// language. {field} and {param} are replaced with the field name and the rule
+// param.
+var validationMessages = map[string]map[string]string{
+	"en": {
+		"":         "{field} is not valid",
+		typeRule:   "{field} has an invalid type",
+		"required": "{field} is required",
+		"email":    "{field} must be a valid email",
+		"url":      "{field} must be a valid URL",
+		"uuid":     "{field} must be a valid UUID",
+		"min":      "{field} must be at least {param}",
+		"max":      "{field} must be at most {param}",
+		"len":      "{field} must have a length of {param}",
+		"gt":       "{field} must be greater than {param}",
+		"gte":      "{field} must be greater than or equal to {param}",
+		"lt":       "{field} must be less than {param}",
+		"lte":      "{field} must be less than or equal to {param}",
+		"oneof":    "{field} must be one of [{param}]",
+		"alphanum": "{field} must contain only letters and numbers",
+		"numeric":  "{field} must be numeric",
+	},
+	"es": {
+		"":         "{field} no es válido",
+		typeRule:   "{field} tiene un tipo no válido",
+		"required": "{field} es obligatorio",
+		"email":    "{field} debe ser un email válido",
+		"url":      "{field} debe ser una URL válida",
+		"uuid":     "{field} debe ser un UUID válido",
+		"min":      "{field} debe ser al menos {param}",
+		"max":      "{field} debe ser como máximo {param}",
+		"len":      "{field} debe tener una longitud de {param}",
+		"gt":       "{field} debe ser mayor que {param}",
+		"gte":      "{field} debe ser mayor o igual que {param}",
+		"lt":       "{field} debe ser menor que {param}",
+		"lte":      "{field} debe ser menor o igual que {param}",
+		"oneof":    "{field} debe ser uno de [{param}]",
+		"alphanum": "{field} solo puede contener letras y números",
+		"numeric":  "{field} debe ser numérico",
+	},
+}
+
+var validationMessagesMutex sync.RWMutex
+
+// RegisterValidationMessages adds or replaces the message templates of a
+// language. Keys are the validator rules; the empty key is the fallback.
+func RegisterValidationMessages(language string, messages map[string]string) {
+	validationMessagesMutex.Lock()
+	defer validationMessagesMutex.Unlock()
+
+	language = strings.ToLower(language)
+	if validationMessages[language] == nil {
+		validationMessages[language] = make(map[string]string, len(messages))
+	}
+	for rule, message := range messages {
+		validationMessages[language][rule] = message
+	}
+}
+
+// BindAndValidate binds the request body to the dto, by the request
+// Content-Type, and validates it with the binding tags. On failure, it sends
+// a 400 with the invalid fields, with messages in the Accept-Language of the
+// request, and returns the error:
+//
+//	if err := response.BindAndValidate(c, &dto); err != nil {
+//		return
+//	}
+func BindAndValidate(c *gin.Context, dto interface{}) error {
+	return bindAndValidate(c, dto, c.ShouldBind, formTag, func() map[string][]string {
+		return c.Request.PostForm
+	})
+}
+
+// BindQueryAndValidate is the BindAndValidate version for query params.
+func BindQueryAndValidate(c *gin.Context, dto interface{}) error {
+	return bindAndValidate(c, dto, c.ShouldBindQuery, formTag, func() map[string][]string {
+		return c.Request.URL.Query()
+	})
+}
+
+// BindURIAndValidate is the BindAndValidate version for URI params.
+func BindURIAndValidate(c *gin.Context, dto interface{}) error {
+	return bindAndValidate(c, dto, c.ShouldBindUri, uriTag, func() map[string][]string {
+		params := make(map[string][]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = []string{param.Value}
+		}
+		return params
+	})
+}
+
+// bindAndValidate binds the dto. values returns the form, query or URI values
+// that bind read from the tag fields, as gin reports their number parse errors
+// without the field.
+func bindAndValidate(c *gin.Context, dto interface{}, bind func(interface{}) error, tag string, values func() map[string][]string) error {
+	err := bind(dto)
+	if err == nil {
+		return nil
+	}
+
+	fields, ok := getFieldErrors(err, getLanguage(c.GetHeader("Accept-Language")), func(numErr *strconv.NumError) (string, bool) {
+		return findField(reflect.TypeOf(dto), tag, values(), numErr)
+	})
+	if !ok {
+		SendError(c, ErrBadRequest.WithCause(err))
+		return err
+	}
+
+	SendError(c, ErrValidation.WithDetail(fieldsDetail, fields))
+	return err
+}
+
+// init registers the tag names on the gin binding.Validator engine, the one
+// its bindings validate with, so the fields are reported by their json, form
+// or uri names instead of the go ones. As the engine is global, importing the
+// package changes the field names of any validator.FieldError of the service.
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", formTag, uriTag} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// getFieldErrors translates validation and type errors to field errors.
+// Returns false for any other error, as malformed bodies, or number errors
+// whose field can't be found.
+func getFieldErrors(err error, language string, find func(*strconv.NumError) (string, bool)) ([]FieldError, bool) {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, newFieldError(
+				getFieldPath(fieldErr), fieldErr.Tag(), fieldErr.Param(), language,
+			))
+		}
+		return fields, true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{newFieldError(typeErr.Field, typeRule, typeErr.Type.String(), language)}, true
+	}
+
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		field, ok := find(numErr)
+		if !ok {
+			return nil, false
+		}
+		return []FieldError{newFieldError(field, typeRule, numberTypes[numErr.Func], language)}, true
+	}
+
+	return nil, false
+}
+
+// numberTypes are the types expected by the strconv functions.
+var numberTypes = map[string]string{
+	"ParseInt":   "int",
+	"ParseUint":  "uint",
+	"ParseFloat": "float",
+	"ParseBool":  "bool",
+}
+
+// getFieldPath returns the path of the field from the validated struct, as
+// address.city, so nested fields with the same name can be told apart.
+func getFieldPath(fieldErr validator.FieldError) string {
+	namespace := strings.SplitN(fieldErr.Namespace(), ".", 2)
+	if len(namespace) < 2 {
+		return fieldErr.Field()
+	}
+	return namespace[1]
+}
+
+// findField returns the name of the dto field, by its tag, whose value fails
+// to parse as the field type with the value of numErr. Fields without the tag
+// are bound by their go name, and struct fields are walked as gin does.
+func findField(dto reflect.Type, tag string, values map[string][]string, numErr *strconv.NumError) (string, bool) {
+	dto = indirectType(dto)
+	if dto.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	for i := 0; i < dto.NumField(); i++ {
+		field := dto.Field(i)
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := indirectType(field.Type)
+		if fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+			fieldType = indirectType(fieldType.Elem())
+		}
+
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			if name, ok := findField(fieldType, tag, values, numErr); ok {
+				return name, true
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		for _, value := range values[name] {
+			if value == numErr.Num && !parsesAs(fieldType, value) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// parsesAs reports if value can be bound to a field of type t. Only numbers
+// and booleans are checked, as they are the ones failing with a NumError.
+func parsesAs(t reflect.Type, value string) bool {
+	var err error
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == durationType {
+			_, err = time.ParseDuration(value)
+		} else {
+			_, err = strconv.ParseInt(value, 10, t.Bits())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(value, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(value, t.Bits())
+	case reflect.Bool:
+		_, err = strconv.ParseBool(value)
+	default:
+		return true
+	}
+	return err == nil
+}
+
+func newFieldError(field string, rule string, param string, language string) FieldError {
+	validationMessagesMutex.RLock()
+	message, ok := validationMessages[language][rule]
+	if !ok {
+		message = validationMessages[language][""]
+	}
+	validationMessagesMutex.RUnlock()
+
+	message = strings.Replace(message, fieldHolder, field, -1)
+	message = strings.Replace(message, paramHolder, param, -1)
+	return FieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: message,
+	}
+}
+
+// getLanguage returns the preferred language of the Accept-Language header
+// with registered messages, or the default one.
+func getLanguage(header string) string {
+	type accepted struct {
+		language string
+		quality  float64
+	}
+
+	languages := make([]accepted, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(strings.TrimSpace(part), ";")
+		language := strings.ToLower(strings.SplitN(params[0], "-", 2)[0])
+		if language == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				quality, _ = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			}
+		}
+		languages = append(languages, accepted{language, quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	validationMessagesMutex.RLock()
+	defer validationMessagesMutex.RUnlock()
+	for _, accepted := range languages {
+		if _, ok := validationMessages[accepted.language]; ok && accepted.quality > 0 {
+			return accepted.language
+		}
+	}
+	return defaultLanguage
+}
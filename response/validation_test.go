@@ -0,0 +1,89 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testAddress struct {
+	City string `json:"city" binding:"required"`
+}
+
+type testUser struct {
+	Name    string      `json:"name" binding:"required"`
+	Address testAddress `json:"address"`
+}
+
+type testQuery struct {
+	Name string `form:"name"`
+	Page int    `form:"page"`
+}
+
+func decodeFieldErrors(t *testing.T, body []byte) []FieldError {
+	t.Helper()
+	var decoded struct {
+		Details struct {
+			Fields []FieldError `json:"fields"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("unable to decode %s: %v", body, err)
+	}
+	return decoded.Details.Fields
+}
+
+func TestBindAndValidateReportsFieldPaths(t *testing.T) {
+	c, recorder := newTestContext("/")
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"foo"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	if err := BindAndValidate(c, new(testUser)); err == nil {
+		t.Fatal("BindAndValidate accepted an invalid body")
+	}
+
+	fields := decodeFieldErrors(t, recorder.Body.Bytes())
+	if len(fields) != 1 || fields[0].Field != "address.city" || fields[0].Rule != "required" {
+		t.Errorf("fields = %+v, want address.city required", fields)
+	}
+}
+
+func TestBindQueryAndValidateReportsNumberErrors(t *testing.T) {
+	c, recorder := newTestContext("/?page=abc")
+
+	if err := BindQueryAndValidate(c, new(testQuery)); err == nil {
+		t.Fatal("BindQueryAndValidate accepted an invalid page")
+	}
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	fields := decodeFieldErrors(t, recorder.Body.Bytes())
+	if len(fields) != 1 || fields[0].Field != "page" || fields[0].Rule != typeRule || fields[0].Param != "int" {
+		t.Errorf("fields = %+v, want page type int", fields)
+	}
+}
+
+func TestBindQueryAndValidateFindsTheFailingField(t *testing.T) {
+	c, recorder := newTestContext("/?name=abc&page=abc")
+	if err := BindQueryAndValidate(c, new(testQuery)); err == nil {
+		t.Fatal("BindQueryAndValidate accepted an invalid page")
+	}
+
+	fields := decodeFieldErrors(t, recorder.Body.Bytes())
+	if len(fields) != 1 || fields[0].Field != "page" {
+		t.Errorf("fields = %+v, want page", fields)
+	}
+}
+
+func TestFindFieldWithoutMatch(t *testing.T) {
+	numErr := &strconv.NumError{Func: "ParseInt", Num: "abc", Err: strconv.ErrSyntax}
+	values := map[string][]string{"name": {"abc"}}
+	if field, ok := findField(reflect.TypeOf(testQuery{}), formTag, values, numErr); ok {
+		t.Errorf("findField = %q, want no field", field)
+	}
+}
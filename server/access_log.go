@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	accessLogMessage       = "access"
+	accessLogSampleRateKey = "ACCESS_LOG_SAMPLE_RATE"
+	accessLogSkipPathsKey  = "ACCESS_LOG_SKIP_PATHS"
+)
+
+// AccessLogConfig stores the access log middleware configuration.
+type AccessLogConfig struct {
+	// SampleRate is the ratio, between 0 and 1, of successful requests to log.
+	// Requests ending with a 4xx or 5xx status are always logged.
+	SampleRate float64
+	// SkipPaths are paths that are never logged, as health checks.
+	SkipPaths []string
+}
+
+// DefaultAccessLogConfig returns an AccessLogConfig that logs every request,
+// overridden by the ACCESS_LOG_SAMPLE_RATE and ACCESS_LOG_SKIP_PATHS (comma
+// separated) env variables.
+func DefaultAccessLogConfig() AccessLogConfig {
+	config := AccessLogConfig{SampleRate: 1}
+
+	if rate, err := strconv.ParseFloat(os.Getenv(accessLogSampleRateKey), 64); err == nil {
+		config.SampleRate = rate
+	}
+
+	if envExist(accessLogSkipPathsKey) {
+		for _, path := range strings.Split(os.Getenv(accessLogSkipPathsKey), ",") {
+			config.SkipPaths = append(config.SkipPaths, strings.TrimSpace(path))
+		}
+	}
+
+	return config
+}
+
+// AccessLog returns a middleware that logs one structured entry per request
+// with the DefaultAccessLogConfig. It replaces gin.Logger() on the default
+// middleware.
+func AccessLog() gin.HandlerFunc {
+	return AccessLogWithConfig(DefaultAccessLogConfig())
+}
+
+// AccessLogWithConfig returns a middleware that logs one structured entry per
+// request, with the same fields that Log adds plus the response status, sizes
+// and latency on the Stackdriver httpRequest object.
+func AccessLogWithConfig(config AccessLogConfig) gin.HandlerFunc {
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		status := c.Writer.Status()
+		if skip[path] || (status < 400 && !isSampled(config.SampleRate)) {
+			return
+		}
+
+		request := httpRequest(c)
+		request["status"] = status
+		request["requestSize"] = nonNegative(c.Request.ContentLength)
+		request["responseSize"] = nonNegative(int64(c.Writer.Size()))
+		request["latency"] = formatLatency(time.Since(start))
+
+		entry := GetLogger().
+			WithContext(c.Request.Context()).
+			WithFields(requestFields(c)).
+			WithField(httpRequestField, request)
+
+		logAccess(entry, status)
+	}
+}
+
+func isSampled(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
+
+// formatLatency returns the latency as expected by Cloud Logging: seconds with
+// up to nine fractional digits and a "s" suffix.
+func formatLatency(latency time.Duration) string {
+	return fmt.Sprintf("%.9fs", latency.Seconds())
+}
+
+// nonNegative maps the -1 used for unknown sizes to 0.
+func nonNegative(size int64) int64 {
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+func logAccess(entry *logrus.Entry, status int) {
+	switch {
+	case status >= 500:
+		entry.Error(accessLogMessage)
+	case status >= 400:
+		entry.Warn(accessLogMessage)
+	default:
+		entry.Info(accessLogMessage)
+	}
+}
@@ -1,9 +1,25 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/response"
+)
+
+const (
+	badgerMaxPendingWrites = 256
+	badgerBackupSinceKey   = "since"
+	badgerBackupTrailer    = "X-Backup-Since"
+	badgerBackupPath       = "/backup"
+	badgerRestorePath      = "/restore"
 )
 
 // GetInternalDB returns the internal badger DB
@@ -35,8 +51,12 @@ func (s *Service) initInternalDB() error {
 		return InternalDatabaseAlreadyInitializeError()
 	}
 
+	if err := validateInternalDBGC(s.options.internalDB); err != nil {
+		return err
+	}
+
 	var err error
-	onceDB.Do(func() {
+	onceInternalDB.Do(func() {
 		s.badger, err = badger.Open(
 			s.options.internalDB.BadgerOptions,
 		)
@@ -44,13 +64,166 @@ func (s *Service) initInternalDB() error {
 
 	if err == nil {
 		GetLogger().Info("Badger DB configured")
+		s.OnClose("internal database", func(ctx context.Context) error {
+			s.stopInternalDBGC()
+			return s.badger.Close()
+		})
+		s.startInternalDBGC()
 	}
 
 	return err
 }
 
+// validateInternalDBGC defaults a zero discard ratio and checks it only when
+// the value log GC will run, as in-memory databases have no value log.
+func validateInternalDBGC(options *InternalDBOptions) error {
+	if !runsInternalDBGC(options) {
+		return nil
+	}
+
+	if options.GCDiscardRatio == 0 {
+		options.GCDiscardRatio = DefaultBadgerGCDiscardRatio
+	}
+
+	if ratio := options.GCDiscardRatio; ratio <= 0 || ratio >= 1 {
+		return NewInvalidGCDiscardRatioError(ratio)
+	}
+
+	return nil
+}
+
+func runsInternalDBGC(options *InternalDBOptions) bool {
+	return !options.BadgerOptions.InMemory && options.GCInterval > 0
+}
+
+func (s *Service) startInternalDBGC() {
+	options := s.options.internalDB
+	if !runsInternalDBGC(options) {
+		return
+	}
+
+	s.internalDBGCStop = make(chan struct{})
+	s.internalDBGCDone = make(chan struct{})
+	go runInternalDBGC(s.badger, options, s.internalDBGCStop, s.internalDBGCDone)
+}
+
+func runInternalDBGC(db *badger.DB, options *InternalDBOptions, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(options.GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// Each successful call rewrites one value log file, so we keep
+			// calling it until there is nothing left to collect.
+			var err error
+			for err == nil {
+				err = db.RunValueLogGC(options.GCDiscardRatio)
+			}
+			if err != badger.ErrNoRewrite {
+				GetLogger().WithError(err).Warn("Badger value log GC failed")
+			}
+		}
+	}
+}
+
+// stopInternalDBGC waits for the running GC even if the close context is done,
+// as a value log rewrite can't be interrupted and badger can't be closed while
+// it is running.
+func (s *Service) stopInternalDBGC() {
+	if s.internalDBGCStop == nil {
+		return
+	}
+	close(s.internalDBGCStop)
+	<-s.internalDBGCDone
+}
+
+// BackupInternalDB dumps all the internal database entries with a version
+// greater than since into w. It returns the version to use as since on the
+// next incremental backup.
+func (s *Service) BackupInternalDB(w io.Writer, since uint64) (uint64, error) {
+	db, err := s.GetInternalDB()
+	if err != nil {
+		return 0, err
+	}
+
+	return db.Backup(w, since)
+}
+
+// LoadInternalDB restores a backup created with BackupInternalDB. As badger
+// can't load over a database in use, it must be called before Run; once the
+// service is serving it returns an InternalDBInUseError.
+func (s *Service) LoadInternalDB(r io.Reader) error {
+	db, err := s.GetInternalDB()
+	if err != nil {
+		return err
+	}
+
+	if atomic.LoadInt32(&s.serving) == 1 {
+		return NewInternalDBInUseError()
+	}
+
+	return db.Load(r, badgerMaxPendingWrites)
+}
+
+// InternalDBAdmin mounts the backup and restore endpoints of the internal
+// database under relativePath. At least one guard handler is needed, as they
+// expose the whole database. The restore endpoint answers a 409 Conflict while
+// the service is serving: see LoadInternalDB.
+func (s *Service) InternalDBAdmin(relativePath string, guards ...gin.HandlerFunc) error {
+	if len(guards) == 0 {
+		return NewUnprotectedAdminRouteError(relativePath)
+	}
+
+	s.addInternalDBRoutes(s.Group(relativePath, guards...))
+	return nil
+}
+
+func (s *Service) addInternalDBRoutes(group *gin.RouterGroup) {
+	group.GET(badgerBackupPath, s.backupInternalDBHandler)
+	group.POST(badgerRestorePath, s.restoreInternalDBHandler)
+}
+
+func (s *Service) backupInternalDBHandler(c *gin.Context) {
+	since, err := strconv.ParseUint(c.DefaultQuery(badgerBackupSinceKey, "0"), 10, 64)
+	if err != nil {
+		response.SendBadRequest(c, err)
+		return
+	}
+
+	if _, err = s.GetInternalDB(); err != nil {
+		response.SendInternalError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Trailer", badgerBackupTrailer)
+	c.Status(http.StatusOK)
+	version, err := s.BackupInternalDB(c.Writer, since)
+	if err != nil {
+		// Headers are already sent, so we only can log it.
+		GetLogger().WithError(err).Error("Badger backup failed")
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Set(badgerBackupTrailer, strconv.FormatUint(version, 10))
+}
+
+func (s *Service) restoreInternalDBHandler(c *gin.Context) {
+	err := s.LoadInternalDB(c.Request.Body)
+	if err != nil {
+		response.SendError(c, err)
+		return
+	}
+
+	response.SendNoContent(c)
+}
+
 func mustInitializeInternalDB(options *Options) bool {
-	return options.internalDB != nil && options.internalDB.enabled
+	return options.internalDB != nil && options.internalDB.IsEnabled()
 }
 
 // NoInternalDatabaseOptions is used when user try to get the internal
@@ -61,6 +234,11 @@ func (e *NoInternalDatabaseOptions) Error() string {
 	return "Can't initialize Database. Database config is not supplied"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *NoInternalDatabaseOptions) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NoInternalDatabaseOptionsError returns a new NoInternalDatabaseOptionsError error
 func NoInternalDatabaseOptionsError() error {
 	return &NoInternalDatabaseOptions{}
@@ -98,6 +276,11 @@ func (e *InternalDBNotYetInitializeError) Error() string {
 	return fmt.Sprintf("badger is already initialized")
 }
 
+// AppError implements response.AppErrorMapper
+func (e *InternalDBNotYetInitializeError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NewInternalDBNotYetInitializeError returns a new InternalDBNotYetInitializeErrorError error.
 func NewInternalDBNotYetInitializeError() error {
 	return &InternalDBNotYetInitializeError{}
@@ -108,3 +291,48 @@ func IsInternalDBNotYetInitializeError(err error) bool {
 	_, ok := err.(*InternalDBNotYetInitializeError)
 	return ok
 }
+
+// InternalDBInUseError is used when user try to load a backup into the internal
+// database while the service is serving requests.
+type InternalDBInUseError struct{}
+
+func (e *InternalDBInUseError) Error() string {
+	return "Can't load the internal database backup. The service is already serving requests"
+}
+
+// AppError implements response.AppErrorMapper
+func (e *InternalDBInUseError) AppError() *response.AppError {
+	return response.ErrConflict.WithCause(e)
+}
+
+// NewInternalDBInUseError returns a new InternalDBInUseError error.
+func NewInternalDBInUseError() error {
+	return &InternalDBInUseError{}
+}
+
+// IsInternalDBInUseError checks if the error is a InternalDBInUseError error.
+func IsInternalDBInUseError(err error) bool {
+	_, ok := err.(*InternalDBInUseError)
+	return ok
+}
+
+// InvalidGCDiscardRatioError is used when the badger value log GC discard ratio
+// is not in the (0, 1) range.
+type InvalidGCDiscardRatioError struct {
+	ratio float64
+}
+
+func (e *InvalidGCDiscardRatioError) Error() string {
+	return fmt.Sprintf("Invalid badger GC discard ratio %v. It must be greater than 0 and less than 1", e.ratio)
+}
+
+// NewInvalidGCDiscardRatioError returns a new InvalidGCDiscardRatioError error.
+func NewInvalidGCDiscardRatioError(ratio float64) error {
+	return &InvalidGCDiscardRatioError{ratio: ratio}
+}
+
+// IsInvalidGCDiscardRatioError checks if the error is a InvalidGCDiscardRatioError error.
+func IsInvalidGCDiscardRatioError(err error) bool {
+	_, ok := err.(*InvalidGCDiscardRatioError)
+	return ok
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CloseFunc releases a plugin resource. It should return as soon as possible
+// when ctx is done.
+type CloseFunc func(ctx context.Context) error
+
+type closer struct {
+	name  string
+	close CloseFunc
+}
+
+// OnClose registers a function to be called by CloseAll. Use it to attach
+// schedulers, workers or any resource that must be released on shutdown.
+// Registered functions are called in reverse order of registration.
+func (s *Service) OnClose(name string, close CloseFunc) {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+	s.closers = append(s.closers, closer{name: name, close: close})
+}
+
+// CloseAll closes all opened connections and plugins. It is a shortcut for
+// CloseAllContext(context.Background()).
+func (s *Service) CloseAll() error {
+	return s.CloseAllContext(context.Background())
+}
+
+// CloseAllContext closes all opened connections and plugins, registered
+// closers included. It does not stop at the first error: all of them are
+// returned on a CloseError. If ctx is done before a closer returns, CloseAll
+// gives up on it and doesn't start the remaining ones, as they could release
+// resources still used by the closer left running. Subsequent calls return the
+// result of the first one.
+func (s *Service) CloseAllContext(ctx context.Context) error {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+
+	if s.closed {
+		return s.closeErr
+	}
+	s.closed = true
+
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("%v: not closed: %v", s.closers[i].name, ctx.Err()))
+			continue
+		}
+		if err := runCloser(ctx, s.closers[i]); err != nil {
+			GetLogger().WithError(err).Warnf("Can't close %v", s.closers[i].name)
+			errs = append(errs, err)
+		}
+	}
+	s.closers = nil
+
+	if len(errs) > 0 {
+		s.closeErr = NewCloseError(errs)
+	}
+	return s.closeErr
+}
+
+func runCloser(ctx context.Context, c closer) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.close(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%v: %v", c.name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%v: %v", c.name, ctx.Err())
+	}
+}
+
+// CloseError aggregates the errors returned while closing the service.
+type CloseError struct {
+	Errors []error
+}
+
+func (e *CloseError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("Error closing service: %v", strings.Join(messages, "; "))
+}
+
+// NewCloseError returns a new CloseError error.
+func NewCloseError(errors []error) error {
+	return &CloseError{errors}
+}
+
+// IsCloseError checks if the error is a CloseError error.
+func IsCloseError(err error) bool {
+	_, ok := err.(*CloseError)
+	return ok
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -66,14 +67,30 @@ func (s *Service) initDB() error {
 		return DatabaseAlreadyInitializeError()
 	}
 
+	if mustInitializeTracing(s.options) {
+		s.options.db.driverName = tracedDatabaseDriver
+	}
+
 	var err error
 	onceDB.Do(func() {
 		s.db, s.dbx, err = initializeDBFromOptions(s.options.db)
 	})
 
+	if err == nil && s.db != nil {
+		s.OnClose("database", s.closeDB)
+	}
+
 	return err
 }
 
+// closeDB closes the sqlx wrapper, that also closes the wrapped sql database.
+func (s *Service) closeDB(ctx context.Context) error {
+	if s.dbx != nil {
+		return s.dbx.Close()
+	}
+	return s.db.Close()
+}
+
 func mustInitializeDB(options *Options) bool {
 	return options.db != nil
 }
@@ -135,7 +152,7 @@ func connectToDBServer(options *DBOptions) (db *sql.DB, err error) {
 	connectionParams := getServerConnectionString(options)
 	ret := retrier.New(retrier.ExponentialBackoff(5, 1*time.Second), retrier.DefaultClassifier{})
 	ret.Run(func() error {
-		db, err = sql.Open(pluginDatabaseDriver, connectionParams)
+		db, err = sql.Open(options.getDriverName(), connectionParams)
 		if err != nil {
 			return err
 		}
@@ -160,7 +177,7 @@ func getServerConnectionString(options *DBOptions) string {
 
 func connectToDB(options *DBOptions) (*sql.DB, error) {
 	connectionParams := getDBConnectionString(options)
-	db, err := sql.Open(pluginDatabaseDriver, connectionParams)
+	db, err := sql.Open(options.getDriverName(), connectionParams)
 	err = db.Ping()
 	if err != nil {
 		GetLogger().WithError(err).Warn("Error Opening mainDB")
@@ -1,6 +1,10 @@
 package server
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/orov-io/BlackBart/response"
+)
 
 // ServiceNotYetInitialize is used when user try to get the service and it is
 // not initialized
@@ -10,6 +14,11 @@ func (e *ServiceNotYetInitialize) Error() string {
 	return "Error getting service. Service is not yet initialized"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *ServiceNotYetInitialize) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // ServiceNotYetInitializeError returns a new ServiceNotYetInitialize error
 func ServiceNotYetInitializeError() error {
 	return &ServiceNotYetInitialize{}
@@ -36,6 +45,11 @@ func (e *DatabaseNotYetInitialize) Error() string {
 	return "Error getting database. Database is not yet initialized"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *DatabaseNotYetInitialize) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // DatabaseNotYetInitializeError returns a new DatabaseNotYetInitialize error
 func DatabaseNotYetInitializeError() error {
 	return &DatabaseNotYetInitialize{}
@@ -68,6 +82,11 @@ func (e *NoDatabaseOptions) Error() string {
 	return "Can't initialize Database. Database config is not supplied"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *NoDatabaseOptions) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NoDatabaseOptionsError returns a new NoDatabaseOptionsError error
 func NoDatabaseOptionsError() error {
 	return &NoDatabaseOptions{}
@@ -87,6 +106,11 @@ func (e *FirebaseNotAlreadyInitialized) Error() string {
 	return "Can't initialize Firebase. Are firebase config supplied?"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *FirebaseNotAlreadyInitialized) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // FirebaseNotAlreadyInitializedError returns a new FirebaseNotAlreadyInitializedError error
 func FirebaseNotAlreadyInitializedError() error {
 	return &FirebaseNotAlreadyInitialized{}
@@ -106,6 +130,11 @@ func (e *NoFirebaseOptions) Error() string {
 	return "Can't initialize Firebase. Firebase config is not supplied"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *NoFirebaseOptions) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NoFirebaseOptionsError returns a new NoFirebaseOptionsError error
 func NoFirebaseOptionsError() error {
 	return &NoFirebaseOptions{}
@@ -124,6 +153,11 @@ func (e *NoGinOptions) Error() string {
 	return "Can't initialize Gin. Gin config is not supplied"
 }
 
+// AppError implements response.AppErrorMapper
+func (e *NoGinOptions) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NoGinOptionsError returns a new NoGinOptionsError error
 func NoGinOptionsError() error {
 	return &NoGinOptions{}
@@ -142,6 +176,11 @@ func (e *RedisNotYetInitializedError) Error() string {
 	return fmt.Sprintf("Redis is not yet initialized")
 }
 
+// AppError implements response.AppErrorMapper
+func (e *RedisNotYetInitializedError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NewRedisNotYetInitializedError returns a new RedisNotYetInitializedErrorError error
 func NewRedisNotYetInitializedError() error {
 	return &RedisNotYetInitializedError{}
@@ -160,6 +199,11 @@ func (e *NoRedisOptionsError) Error() string {
 	return fmt.Sprintf("No redis options provided")
 }
 
+// AppError implements response.AppErrorMapper
+func (e *NoRedisOptionsError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
 // NewNoRedisOptionsError returns a new NoRedisOptionsErrorError error.
 func NewNoRedisOptionsError() error {
 	return &NoRedisOptionsError{}
@@ -188,3 +232,207 @@ func IsRedisPoolAlreadyInitializedError(err error) bool {
 	_, ok := err.(*RedisPoolAlreadyInitializedError)
 	return ok
 }
+
+// UnprotectedAdminRouteError is used when an admin route is mounted without guards.
+type UnprotectedAdminRouteError struct {
+	path string
+}
+
+func (e *UnprotectedAdminRouteError) Error() string {
+	return fmt.Sprintf("Refusing to mount admin route %v without guard handlers", e.path)
+}
+
+// NewUnprotectedAdminRouteError returns a new UnprotectedAdminRouteError error.
+func NewUnprotectedAdminRouteError(path string) error {
+	return &UnprotectedAdminRouteError{path}
+}
+
+// IsUnprotectedAdminRouteError checks if the error is a UnprotectedAdminRouteError error.
+func IsUnprotectedAdminRouteError(err error) bool {
+	_, ok := err.(*UnprotectedAdminRouteError)
+	return ok
+}
+
+// MetricsNotYetInitializedError is used when metrics are not yet initialized.
+type MetricsNotYetInitializedError struct{}
+
+func (e *MetricsNotYetInitializedError) Error() string {
+	return fmt.Sprintf("Metrics are not yet initialized")
+}
+
+// AppError implements response.AppErrorMapper
+func (e *MetricsNotYetInitializedError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
+// NewMetricsNotYetInitializedError returns a new MetricsNotYetInitializedError error.
+func NewMetricsNotYetInitializedError() error {
+	return &MetricsNotYetInitializedError{}
+}
+
+// IsMetricsNotYetInitializedError checks if the error is a MetricsNotYetInitializedError error.
+func IsMetricsNotYetInitializedError(err error) bool {
+	_, ok := err.(*MetricsNotYetInitializedError)
+	return ok
+}
+
+// NoMetricsOptionsError is used when metrics configuration is not supplied.
+type NoMetricsOptionsError struct{}
+
+func (e *NoMetricsOptionsError) Error() string {
+	return fmt.Sprintf("No metrics options provided")
+}
+
+// AppError implements response.AppErrorMapper
+func (e *NoMetricsOptionsError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
+// NewNoMetricsOptionsError returns a new NoMetricsOptionsError error.
+func NewNoMetricsOptionsError() error {
+	return &NoMetricsOptionsError{}
+}
+
+// IsNoMetricsOptionsError checks if the error is a NoMetricsOptionsError error.
+func IsNoMetricsOptionsError(err error) bool {
+	_, ok := err.(*NoMetricsOptionsError)
+	return ok
+}
+
+// NoTracingOptionsError is used when tracing configuration is not supplied.
+type NoTracingOptionsError struct{}
+
+func (e *NoTracingOptionsError) Error() string {
+	return fmt.Sprintf("No tracing options provided")
+}
+
+// AppError implements response.AppErrorMapper
+func (e *NoTracingOptionsError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
+// NewNoTracingOptionsError returns a new NoTracingOptionsError error.
+func NewNoTracingOptionsError() error {
+	return &NoTracingOptionsError{}
+}
+
+// IsNoTracingOptionsError checks if the error is a NoTracingOptionsError error.
+func IsNoTracingOptionsError(err error) bool {
+	_, ok := err.(*NoTracingOptionsError)
+	return ok
+}
+
+// NoProfilerOptionsError is used when the profiler is not enabled.
+type NoProfilerOptionsError struct{}
+
+func (e *NoProfilerOptionsError) Error() string {
+	return fmt.Sprintf("Profiler is not enabled")
+}
+
+// AppError implements response.AppErrorMapper
+func (e *NoProfilerOptionsError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
+// NewNoProfilerOptionsError returns a new NoProfilerOptionsError error.
+func NewNoProfilerOptionsError() error {
+	return &NoProfilerOptionsError{}
+}
+
+// IsNoProfilerOptionsError checks if the error is a NoProfilerOptionsError error.
+func IsNoProfilerOptionsError(err error) bool {
+	_, ok := err.(*NoProfilerOptionsError)
+	return ok
+}
+
+// UnknownProfilerModeError is used when the profiler mode is not supported.
+type UnknownProfilerModeError struct {
+	mode string
+}
+
+func (e *UnknownProfilerModeError) Error() string {
+	return fmt.Sprintf("Unknown profiler mode %v", e.mode)
+}
+
+// NewUnknownProfilerModeError returns a new UnknownProfilerModeError error.
+func NewUnknownProfilerModeError(mode string) error {
+	return &UnknownProfilerModeError{mode}
+}
+
+// IsUnknownProfilerModeError checks if the error is a UnknownProfilerModeError error.
+func IsUnknownProfilerModeError(err error) bool {
+	_, ok := err.(*UnknownProfilerModeError)
+	return ok
+}
+
+// PprofWithoutAdminError is used when the profiler is on pprof mode but the
+// admin routes, where the pprof endpoints are served, are not enabled.
+type PprofWithoutAdminError struct{}
+
+func (e *PprofWithoutAdminError) Error() string {
+	return "Can't serve pprof endpoints. Admin routes are not enabled"
+}
+
+// NewPprofWithoutAdminError returns a new PprofWithoutAdminError error.
+func NewPprofWithoutAdminError() error {
+	return &PprofWithoutAdminError{}
+}
+
+// IsPprofWithoutAdminError checks if the error is a PprofWithoutAdminError error.
+func IsPprofWithoutAdminError(err error) bool {
+	_, ok := err.(*PprofWithoutAdminError)
+	return ok
+}
+
+// NoAdminOptionsError is used when admin configuration is not supplied.
+type NoAdminOptionsError struct{}
+
+func (e *NoAdminOptionsError) Error() string {
+	return fmt.Sprintf("No admin options provided")
+}
+
+// AppError implements response.AppErrorMapper
+func (e *NoAdminOptionsError) AppError() *response.AppError {
+	return serviceUnavailable(e)
+}
+
+// NewNoAdminOptionsError returns a new NoAdminOptionsError error.
+func NewNoAdminOptionsError() error {
+	return &NoAdminOptionsError{}
+}
+
+// IsNoAdminOptionsError checks if the error is a NoAdminOptionsError error.
+func IsNoAdminOptionsError(err error) bool {
+	_, ok := err.(*NoAdminOptionsError)
+	return ok
+}
+
+// serviceUnavailable maps the errors of missing or not yet initialized plugins
+// to the response catalog, so handlers that send them with response.SendError
+// answer with a 503 instead of a 500. The error is kept as the hidden cause.
+func serviceUnavailable(err error) *response.AppError {
+	return response.ErrServiceUnavailable.WithCause(err)
+}
+
+// UnauthenticatedWebSocketError is used when a WebSocket endpoint is mounted
+// with no guards and firebase auth is not initialized.
+type UnauthenticatedWebSocketError struct {
+	Path string
+}
+
+func (e *UnauthenticatedWebSocketError) Error() string {
+	return fmt.Sprintf("Refusing to mount the WebSocket endpoint %v without auth. Provide guards or firebase options", e.Path)
+}
+
+// NewUnauthenticatedWebSocketError returns a new UnauthenticatedWebSocketError
+// error.
+func NewUnauthenticatedWebSocketError(path string) error {
+	return &UnauthenticatedWebSocketError{path}
+}
+
+// IsUnauthenticatedWebSocketError checks if the error is a
+// UnauthenticatedWebSocketError error.
+func IsUnauthenticatedWebSocketError(err error) bool {
+	_, ok := err.(*UnauthenticatedWebSocketError)
+	return ok
+}
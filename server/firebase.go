@@ -4,16 +4,26 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"cloud.google.com/go/storage"
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/orov-io/BlackBart/response"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/api/option"
 )
 
 const defaultFirebaseConfigName = "firebase.json"
 
+// FirebaseTokenKey is the gin context key where FirebaseAuth stores the
+// verified *auth.Token.
+const FirebaseTokenKey = "firebase_token"
+
+const accessTokenParam = "access_token"
+
 // GetAuthClient returns the auth client attached to current service
 func GetAuthClient() (*auth.Client, error) {
 	service, err := GetService()
@@ -54,6 +64,47 @@ func GetFirebaseApp() (*firebase.App, error) {
 	return firebaseApp, err
 }
 
+// FirebaseAuth verifies the Firebase ID token sent on the
+// "Authorization: Bearer <token>" header. WebSocket upgrade requests can send
+// it on the access_token query param, as browsers can't set their headers.
+// The user ID is stored on the UIDKey and the token on the FirebaseTokenKey.
+func (s *Service) FirebaseAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, err := s.GetAuthClient()
+		if err != nil {
+			response.SendError(c, err)
+			return
+		}
+
+		idToken := getBearerToken(c)
+		if idToken == "" {
+			response.SendUnauthorizedAccess(c)
+			return
+		}
+
+		token, err := client.VerifyIDToken(c.Request.Context(), idToken)
+		if err != nil {
+			response.SendUnauthorizedAccess(c, err)
+			return
+		}
+
+		c.Set(UIDKey, token.UID)
+		c.Set(FirebaseTokenKey, token)
+		c.Next()
+	}
+}
+
+func getBearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if strings.HasPrefix(header, bearerPrefix) {
+		return strings.TrimPrefix(header, bearerPrefix)
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		return c.Query(accessTokenParam)
+	}
+	return ""
+}
+
 func (s *Service) initAuth() error {
 	if !mustInitializeFirebase(s.options) {
 		return NoFirebaseOptionsError()
@@ -63,7 +114,17 @@ func (s *Service) initAuth() error {
 	if err != nil {
 		return err
 	}
+	s.firebaseMutex.Lock()
 	s.firebase = initAuthApp(credentials)
+	s.firebaseMutex.Unlock()
+	s.OnClose("firebase", func(ctx context.Context) error {
+		// firebase.App holds no connections. Clients are created on demand,
+		// so we only need to stop handing them out.
+		s.firebaseMutex.Lock()
+		defer s.firebaseMutex.Unlock()
+		s.firebase = nil
+		return nil
+	})
 
 	return nil
 }
@@ -93,10 +154,12 @@ func (s *Service) getCredentialsFromBucket() []byte {
 	if err != nil {
 		GetLogger().WithError(err).Fatalf("Can't get storage connection")
 	}
+	defer storageClient.Close()
 	credentials, err := storageClient.Bucket(bucket).Object(name).NewReader(ctx)
 	if err != nil {
 		GetLogger().WithError(err).Fatal("Can't stablish reader connection")
 	}
+	defer credentials.Close()
 
 	buffer, err := ioutil.ReadAll(credentials)
 	if err != nil {
@@ -120,6 +183,7 @@ func (s *Service) getCredentialsFromFile(filePath string) []byte {
 	if err != nil {
 		GetLogger().WithError(err).Fatalf("Can't open credentials file")
 	}
+	defer file.Close()
 
 	fileStats, err := file.Stat()
 	if err != nil {
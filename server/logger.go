@@ -1,11 +1,44 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
 	stackdriver "github.com/TV4/logrus-stackdriver-formatter"
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/env"
+	"github.com/orov-io/BlackBart/requestid"
 	"github.com/sirupsen/logrus"
 )
 
-var log = logrus.New()
+// UIDKey is the gin context key where auth middleware must store the
+// authenticated user ID to be logged by Log and AccessLog.
+const UIDKey = "uid"
+
+const (
+	routeField       = "route"
+	methodField      = "method"
+	uidField         = "uid"
+	httpRequestField = "httpRequest"
+	traceField       = "logging.googleapis.com/trace"
+	traceHeader      = "X-Cloud-Trace-Context"
+	projectKey       = "GCLOUD_PROJECT"
+)
+
+var log = newLogger()
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(&lockedWriter{w: os.Stderr})
+	logger.AddHook(&requestIDHook{})
+	logger.AddHook(&traceHook{})
+	return logger
+}
 
 // setLogger is called by the service. It adds the logger if their option is sets
 // when you initialize the service.
@@ -16,6 +49,23 @@ func setLogger(options *LoggerOptions) {
 	}
 
 	log.Level = options.Level
+	setBaseLogLevel(options.Level)
+
+	switch len(options.Outputs) {
+	case 0:
+	case 1:
+		log.SetOutput(&lockedWriter{w: options.Outputs[0]})
+	default:
+		log.SetOutput(&lockedWriter{w: io.MultiWriter(options.Outputs...)})
+	}
+
+	if len(options.RedactFields) > 0 {
+		log.AddHook(NewRedactHook(options.RedactFields...))
+	}
+
+	for _, hook := range options.Hooks {
+		log.AddHook(hook)
+	}
 
 	log.Infof("Start to loggin with %v config", options.Env)
 }
@@ -25,6 +75,98 @@ func GetLogger() *logrus.Logger {
 	return log
 }
 
+// Log returns a log entry preloaded with the request ID, route, method,
+// authenticated UID and the Stackdriver httpRequest fields of the request.
+// It honors the "route:" scoped log level of the request route.
+func Log(c *gin.Context) *logrus.Entry {
+	return getScopedLogger(RouteScope+c.FullPath()).
+		WithContext(c.Request.Context()).
+		WithFields(requestFields(c)).
+		WithField(httpRequestField, httpRequest(c))
+}
+
+func requestFields(c *gin.Context) logrus.Fields {
+	fields := logrus.Fields{
+		routeField:  c.FullPath(),
+		methodField: c.Request.Method,
+	}
+
+	if id := requestid.Get(c); id != "" {
+		fields[requestid.Key] = id
+	}
+
+	if uid := c.GetString(UIDKey); uid != "" {
+		fields[uidField] = uid
+	}
+
+	if trace := getCloudTrace(c); trace != "" {
+		fields[traceField] = trace
+	}
+
+	return fields
+}
+
+// getCloudTrace returns the trace resource name of the X-Cloud-Trace-Context
+// header, so Cloud Logging groups the request entries under its trace.
+func getCloudTrace(c *gin.Context) string {
+	header := c.GetHeader(traceHeader)
+	if header == "" {
+		return ""
+	}
+
+	return cloudTraceName(strings.SplitN(header, "/", 2)[0])
+}
+
+// cloudTraceName returns the Cloud Trace resource name of the trace ID on the
+// GCLOUD_PROJECT project, or an empty string if the project is not set.
+func cloudTraceName(traceID string) string {
+	project := os.Getenv(projectKey)
+	if project == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("projects/%v/traces/%v", project, traceID)
+}
+
+// httpRequest returns the request data with the field names of the
+// Stackdriver LogEntry httpRequest object.
+func httpRequest(c *gin.Context) map[string]interface{} {
+	return map[string]interface{}{
+		"requestMethod": c.Request.Method,
+		"requestUrl":    redactURL(c.Request.URL).String(),
+		"userAgent":     c.Request.UserAgent(),
+		"remoteIp":      c.ClientIP(),
+		"referer":       c.Request.Referer(),
+		"protocol":      c.Request.Proto,
+	}
+}
+
+// redactURL returns a copy of the URL with the access_token query param
+// masked, as it carries the ID token of the WebSocket requests. Only the token
+// values are replaced, so the other params are kept as they were sent.
+func redactURL(requestURL *url.URL) *url.URL {
+	params := strings.Split(requestURL.RawQuery, "&")
+	redacted := false
+	for i, param := range params {
+		name := strings.SplitN(param, "=", 2)[0]
+		key, err := url.QueryUnescape(name)
+		if err != nil {
+			key = name
+		}
+		if key == accessTokenParam {
+			params[i] = name + "=" + maskedValue
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return requestURL
+	}
+	redactedURL := *requestURL
+	redactedURL.RawQuery = strings.Join(params, "&")
+	return &redactedURL
+}
+
 func localLogging() (logrus.Level, logrus.Formatter) {
 	return logrus.TraceLevel, nil
 }
@@ -38,23 +180,109 @@ func prodServerLogging() (logrus.Level, logrus.Formatter) {
 }
 
 func getLoggerForServer() logrus.Formatter {
-	return stackdriver.NewFormatter(
-		stackdriver.WithService(GetEnvOrDefaultString(serviceNameKey)),
-		stackdriver.WithVersion(GetEnvOrDefaultString(serviceVersionKey)),
-	)
+	return &cloudLoggingFormatter{
+		Formatter: stackdriver.NewFormatter(
+			stackdriver.WithService(GetEnvOrDefaultString(serviceNameKey)),
+			stackdriver.WithVersion(GetEnvOrDefaultString(serviceVersionKey)),
+		),
+	}
 }
 
-func getDefaultLoggerConfByEnv(env string) (logrus.Level, logrus.Formatter) {
-	switch env {
-	case "local":
+// cloudLoggingFields are the LogEntry fields that Cloud Logging only reads
+// from the top level of the JSON payload.
+var cloudLoggingFields = []string{httpRequestField, traceField, spanIDField}
+
+// cloudLoggingFormatter writes the cloudLoggingFields at the top level of the
+// entries formatted by the stackdriver formatter, that nests any field other
+// than its own types under context.data.
+type cloudLoggingFormatter struct {
+	logrus.Formatter
+}
+
+func (f *cloudLoggingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	promoted := make(map[string]interface{})
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+	for _, key := range cloudLoggingFields {
+		if value, ok := data[key]; ok {
+			promoted[key] = value
+			delete(data, key)
+		}
+	}
+
+	if len(promoted) == 0 {
+		return f.Formatter.Format(entry)
+	}
+
+	formatted, err := f.Formatter.Format(&logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Caller:  entry.Caller,
+		Message: entry.Message,
+		Context: entry.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make(map[string]interface{})
+	if err = json.Unmarshal(formatted, &payload); err != nil {
+		return nil, err
+	}
+	for key, value := range promoted {
+		payload[key] = value
+	}
+
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return append(serialized, '\n'), nil
+}
+
+func getDefaultLoggerConfByEnv(environment string) (logrus.Level, logrus.Formatter) {
+	switch strings.ToLower(environment) {
+	case env.Local:
 		return localLogging()
-	case "dev":
+	case env.Development:
 		return nonProdServerLogging()
-	case "pre":
+	case env.PreProduction:
 		return nonProdServerLogging()
-	case "prod":
+	case env.Production:
 		return prodServerLogging()
 	}
 
 	return nonProdServerLogging()
 }
+
+// lockedWriter serializes the writes of the service logger and the scoped
+// loggers that share its output, as each logrus.Logger only locks its own.
+type lockedWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.w.Write(p)
+}
+
+// requestIDHook adds the request ID to entries logged with a request context,
+// as in GetLogger().WithContext(c.Request.Context())
+type requestIDHook struct{}
+
+func (h *requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *requestIDHook) Fire(entry *logrus.Entry) error {
+	if id := requestid.FromContext(entry.Context); id != "" {
+		entry.Data[requestid.Key] = id
+	}
+	return nil
+}
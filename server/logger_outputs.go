@@ -0,0 +1,243 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	logLevelKey          = "LOG_LEVEL"
+	logFormatKey         = "LOG_FORMAT"
+	logOutputKey         = "LOG_OUTPUT"
+	logFileMaxSizeKey    = "LOG_FILE_MAX_SIZE"
+	logFileMaxBackupsKey = "LOG_FILE_MAX_BACKUPS"
+	logRedactKey         = "LOG_REDACT_FIELDS"
+	logLevelSignalsKey   = "LOG_LEVEL_SIGNALS"
+
+	stdoutOutput     = "stdout"
+	stderrOutput     = "stderr"
+	syslogOutput     = "syslog"
+	fileOutputPrefix = "file:"
+
+	redactedValue = "[REDACTED]"
+)
+
+// Log formats accepted by LOG_FORMAT.
+const (
+	TextFormat        = "text"
+	JSONFormat        = "json"
+	StackdriverFormat = "stackdriver"
+	LogfmtFormat      = "logfmt"
+)
+
+// Default rotating file params.
+const (
+	DefaultLogFileMaxSize    = 100 << 20
+	DefaultLogFileMaxBackups = 5
+)
+
+// DefaultRedactedFields are the field names, or parts of them, whose values
+// are never written to the logs.
+var DefaultRedactedFields = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "cookie"}
+
+// GetFormatter returns the logrus formatter for one of the log formats.
+func GetFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case TextFormat:
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case JSONFormat:
+		return &logrus.JSONFormatter{}, nil
+	case StackdriverFormat:
+		return getLoggerForServer(), nil
+	case LogfmtFormat:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	}
+	return nil, fmt.Errorf("Unknown log format %v", format)
+}
+
+// GetOutputs parses a comma separated list of log outputs: stdout, stderr,
+// syslog or file:/path/to/file. Files are rotated by size.
+func GetOutputs(outputs string) ([]io.Writer, error) {
+	writers := make([]io.Writer, 0)
+	for _, output := range strings.Split(outputs, ",") {
+		output = strings.TrimSpace(output)
+		switch {
+		case output == "":
+			continue
+		case output == stdoutOutput:
+			writers = append(writers, os.Stdout)
+		case output == stderrOutput:
+			writers = append(writers, os.Stderr)
+		case output == syslogOutput:
+			writer, err := newSyslogWriter()
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, writer)
+		case strings.HasPrefix(output, fileOutputPrefix):
+			writer, err := NewRotatingFile(strings.TrimPrefix(output, fileOutputPrefix), getLogFileMaxSize(), getLogFileMaxBackups())
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, writer)
+		default:
+			return nil, fmt.Errorf("Unknown log output %v", output)
+		}
+	}
+	return writers, nil
+}
+
+func getLogFileMaxSize() int64 {
+	size, err := strconv.ParseInt(os.Getenv(logFileMaxSizeKey), 10, 64)
+	if err != nil {
+		return DefaultLogFileMaxSize
+	}
+	return size
+}
+
+func getLogFileMaxBackups() int {
+	backups, err := strconv.Atoi(os.Getenv(logFileMaxBackupsKey))
+	if err != nil {
+		return DefaultLogFileMaxBackups
+	}
+	return backups
+}
+
+// RotatingFile is a file writer that rotates the file when it reaches its max
+// size, keeping up to maxBackups old files as path.1, path.2...
+type RotatingFile struct {
+	mutex      sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens, or creates, the file at path to append logs on it.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	return r, r.open()
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize && r.size > 0 {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.maxBackups <= 0 {
+		os.Remove(r.path)
+	}
+	for i := r.maxBackups; i > 0; i-- {
+		source := r.path
+		if i > 1 {
+			source = fmt.Sprintf("%v.%d", r.path, i-1)
+		}
+		// Missing backups are expected until the first rotations are done.
+		os.Rename(source, fmt.Sprintf("%v.%d", r.path, i))
+	}
+
+	return r.open()
+}
+
+// Close implements io.Closer
+func (r *RotatingFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
+
+// redactHook replaces the value of sensitive fields before they are written.
+type redactHook struct {
+	fields []string
+}
+
+// NewRedactHook returns a hook that replaces with [REDACTED] the value of the
+// fields whose name contains one of the provided names, case insensitive.
+func NewRedactHook(fields ...string) logrus.Hook {
+	lowered := make([]string, len(fields))
+	for i, field := range fields {
+		lowered[i] = strings.ToLower(field)
+	}
+	return &redactHook{fields: lowered}
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	var redacted logrus.Fields
+	for key := range entry.Data {
+		if !h.mustRedact(key) {
+			continue
+		}
+		if redacted == nil {
+			// Data can be shared with the parent entry, so we work on a copy.
+			redacted = make(logrus.Fields, len(entry.Data))
+			for k, v := range entry.Data {
+				redacted[k] = v
+			}
+		}
+		redacted[key] = redactedValue
+	}
+
+	if redacted != nil {
+		entry.Data = redacted
+	}
+	return nil
+}
+
+func (h *redactHook) mustRedact(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range h.fields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+// +build !windows,!plan9
+
+package server
+
+import (
+	"io"
+	"log/syslog"
+)
+
+func newSyslogWriter() (io.Writer, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, GetEnvOrDefaultString(serviceNameKey))
+}
@@ -0,0 +1,12 @@
+// +build windows plan9
+
+package server
+
+import (
+	"fmt"
+	"io"
+)
+
+func newSyslogWriter() (io.Writer, error) {
+	return nil, fmt.Errorf("syslog log output is not supported on this platform")
+}
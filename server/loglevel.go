@@ -0,0 +1,245 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/response"
+	"github.com/sirupsen/logrus"
+)
+
+// Log level override scopes prefixes. See SetScopedLogLevel.
+const (
+	RouteScope   = "route:"
+	PackageScope = "package:"
+
+	packageField = "package"
+)
+
+type levelOverride struct {
+	level   logrus.Level
+	expires time.Time
+	once    sync.Once
+	logger  *logrus.Logger
+}
+
+func (o *levelOverride) isExpired(now time.Time) bool {
+	return !o.expires.IsZero() && now.After(o.expires)
+}
+
+// levelManager stores the configured log level and the runtime overrides.
+type levelManager struct {
+	mutex  sync.RWMutex
+	base   logrus.Level
+	timer  *time.Timer
+	scopes map[string]*levelOverride
+}
+
+var levels = &levelManager{
+	base:   logrus.InfoLevel,
+	scopes: make(map[string]*levelOverride),
+}
+
+// SetLogLevel changes the service log level at runtime. If ttl is greater than
+// zero, the configured level is restored after it.
+func SetLogLevel(level logrus.Level, ttl time.Duration) {
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+
+	if levels.timer != nil {
+		levels.timer.Stop()
+		levels.timer = nil
+	}
+
+	log.SetLevel(level)
+	if ttl > 0 {
+		levels.timer = time.AfterFunc(ttl, func() {
+			levels.mutex.Lock()
+			defer levels.mutex.Unlock()
+			log.SetLevel(levels.base)
+			levels.timer = nil
+		})
+	}
+}
+
+// SetScopedLogLevel changes the log level only for the entries logged with
+// Log(c) on the "route:/full/path/:param" scope, or with PackageLog(name) on
+// the "package:name" scope. If ttl is greater than zero, the override expires
+// after it.
+func SetScopedLogLevel(scope string, level logrus.Level, ttl time.Duration) {
+	override := &levelOverride{level: level}
+	if ttl > 0 {
+		override.expires = time.Now().Add(ttl)
+	}
+
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+	levels.scopes[scope] = override
+}
+
+// ResetLogLevels restores the configured log level and removes all the
+// scoped overrides.
+func ResetLogLevels() {
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+
+	if levels.timer != nil {
+		levels.timer.Stop()
+		levels.timer = nil
+	}
+	log.SetLevel(levels.base)
+	levels.scopes = make(map[string]*levelOverride)
+}
+
+// PackageLog returns a log entry for the named package that honors the
+// "package:name" scoped log level.
+func PackageLog(name string) *logrus.Entry {
+	return getScopedLogger(PackageScope+name).WithField(packageField, name)
+}
+
+// getScopedLogger returns a logger that shares the service logger output,
+// formatter and hooks, with the scope level. It is built once per override.
+// If there is no active override for the scope, the service logger is returned.
+func getScopedLogger(scope string) *logrus.Logger {
+	levels.mutex.RLock()
+	override, ok := levels.scopes[scope]
+	levels.mutex.RUnlock()
+
+	if !ok {
+		return log
+	}
+
+	if override.isExpired(time.Now()) {
+		levels.mutex.Lock()
+		if levels.scopes[scope] == override {
+			delete(levels.scopes, scope)
+		}
+		levels.mutex.Unlock()
+		return log
+	}
+
+	override.once.Do(func() {
+		override.logger = &logrus.Logger{
+			Out:          log.Out,
+			Hooks:        log.Hooks,
+			Formatter:    log.Formatter,
+			ReportCaller: log.ReportCaller,
+			Level:        override.level,
+			ExitFunc:     log.ExitFunc,
+		}
+	})
+	return override.logger
+}
+
+func setBaseLogLevel(level logrus.Level) {
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+	levels.base = level
+}
+
+// LogLevelsStatus models the log levels in use.
+type LogLevelsStatus struct {
+	Level  string                 `json:"level"`
+	Base   string                 `json:"base"`
+	Scopes map[string]ScopedLevel `json:"scopes,omitempty"`
+}
+
+// ScopedLevel models an active scoped log level override.
+type ScopedLevel struct {
+	Level   string     `json:"level"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// GetLogLevels returns the log levels in use.
+func GetLogLevels() *LogLevelsStatus {
+	levels.mutex.RLock()
+	defer levels.mutex.RUnlock()
+
+	now := time.Now()
+	status := &LogLevelsStatus{
+		Level:  log.GetLevel().String(),
+		Base:   levels.base.String(),
+		Scopes: make(map[string]ScopedLevel),
+	}
+	for scope, override := range levels.scopes {
+		if override.isExpired(now) {
+			continue
+		}
+		scoped := ScopedLevel{Level: override.level.String()}
+		if !override.expires.IsZero() {
+			expires := override.expires
+			scoped.Expires = &expires
+		}
+		status.Scopes[scope] = scoped
+	}
+	return status
+}
+
+// LogLevelRequest is the body expected by the log level admin endpoint.
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+	// Scope is optional. See SetScopedLogLevel.
+	Scope string `json:"scope"`
+	// TTL is optional, as "10m".
+	TTL string `json:"ttl"`
+}
+
+// LogLevelAdmin mounts the log level endpoints under relativePath: GET returns
+// the levels in use, PUT changes a level with a LogLevelRequest body and
+// DELETE restores the configured levels. At least one guard handler is needed.
+func (s *Service) LogLevelAdmin(relativePath string, guards ...gin.HandlerFunc) error {
+	if len(guards) == 0 {
+		return NewUnprotectedAdminRouteError(relativePath)
+	}
+
+	addLogLevelRoutes(s.Group(relativePath, guards...))
+	return nil
+}
+
+func addLogLevelRoutes(group *gin.RouterGroup) {
+	group.GET("", getLogLevelsHandler)
+	group.PUT("", setLogLevelHandler)
+	group.DELETE("", resetLogLevelsHandler)
+}
+
+func getLogLevelsHandler(c *gin.Context) {
+	response.SendOK(c, GetLogLevels())
+}
+
+func setLogLevelHandler(c *gin.Context) {
+	request := new(LogLevelRequest)
+	if err := c.ShouldBindJSON(request); err != nil {
+		response.SendBadRequest(c, err)
+		return
+	}
+
+	level, err := logrus.ParseLevel(request.Level)
+	if err != nil {
+		response.SendBadRequest(c, err)
+		return
+	}
+
+	var ttl time.Duration
+	if request.TTL != "" {
+		if ttl, err = time.ParseDuration(request.TTL); err != nil {
+			response.SendBadRequest(c, err)
+			return
+		}
+	}
+
+	if request.Scope == "" {
+		SetLogLevel(level, ttl)
+	} else {
+		SetScopedLogLevel(request.Scope, level, ttl)
+	}
+
+	Log(c).Warnf("Log level of scope %q changed to %v", request.Scope, level)
+	response.SendOK(c, GetLogLevels())
+}
+
+func resetLogLevelsHandler(c *gin.Context) {
+	ResetLogLevels()
+	Log(c).Warn("Log levels restored")
+	response.SendNoContent(c)
+}
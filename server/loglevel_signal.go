@@ -0,0 +1,48 @@
+// +build !windows
+
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handleLogLevelSignals makes SIGUSR1 increase the log verbosity one level,
+// up to trace, and SIGUSR2 restore the configured log levels.
+func (s *Service) handleLogLevelSignals() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGUSR2 {
+					ResetLogLevels()
+					GetLogger().Warnf("Log levels restored by %v", sig)
+					continue
+				}
+
+				level := GetLogger().GetLevel()
+				if level < logrus.TraceLevel {
+					level++
+				}
+				SetLogLevel(level, 0)
+				GetLogger().Warnf("Log level changed to %v by %v", level, sig)
+			}
+		}
+	}()
+
+	s.OnClose("log level signals", func(ctx context.Context) error {
+		signal.Stop(signals)
+		close(done)
+		return nil
+	})
+}
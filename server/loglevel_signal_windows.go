@@ -0,0 +1,7 @@
+package server
+
+// handleLogLevelSignals does nothing, as there are no SIGUSR1/SIGUSR2 signals
+// on windows. Use the LogLevelAdmin endpoints instead.
+func (s *Service) handleLogLevelSignals() {
+	GetLogger().Debug("Log level signals are not supported on windows")
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsFlagKey      = "ENABLE_METRICS"
+	metricsPathKey      = "METRICS_PATH"
+	metricsNamespaceKey = "METRICS_NAMESPACE"
+
+	defaultMetricsPath = "/metrics"
+	unmatchedRoute     = "unmatched"
+)
+
+// MetricsOptions stores the prometheus metrics configuration.
+type MetricsOptions struct {
+	// Path is the route of the metrics endpoint.
+	Path string
+	// Namespace prefixes all the provided metric names.
+	Namespace string
+	// Guards are handlers executed before the metrics endpoint, as auth.
+	Guards []gin.HandlerFunc
+}
+
+// NewMetricsOptions returns a MetricsOptions struct with the default path.
+func NewMetricsOptions() *MetricsOptions {
+	return &MetricsOptions{Path: defaultMetricsPath}
+}
+
+// DefaultMetricsOptions returns a MetricsOptions filled with the METRICS_PATH
+// and METRICS_NAMESPACE env variables if ENABLE_METRICS is true. Returns nil
+// otherwise.
+func DefaultMetricsOptions() *MetricsOptions {
+	enable, _ := strconv.ParseBool(os.Getenv(metricsFlagKey))
+	if !enable {
+		return nil
+	}
+
+	options := NewMetricsOptions()
+	if envExist(metricsPathKey) {
+		options.Path = os.Getenv(metricsPathKey)
+	}
+	options.Namespace = os.Getenv(metricsNamespaceKey)
+	return options
+}
+
+// GetMetricsRegistry returns the prometheus registry of the service. Register
+// your own collectors on it.
+func (s *Service) GetMetricsRegistry() (*prometheus.Registry, error) {
+	if s.metrics == nil {
+		return nil, NewMetricsNotYetInitializedError()
+	}
+	return s.metrics, nil
+}
+
+// NewCounter registers and returns a custom counter on the service registry.
+func (s *Service) NewCounter(name string, help string, labels ...string) (*prometheus.CounterVec, error) {
+	if _, err := s.GetMetricsRegistry(); err != nil {
+		return nil, err
+	}
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: s.options.metrics.Namespace,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	return counter, s.registerMetric(counter)
+}
+
+// NewGauge registers and returns a custom gauge on the service registry.
+func (s *Service) NewGauge(name string, help string, labels ...string) (*prometheus.GaugeVec, error) {
+	if _, err := s.GetMetricsRegistry(); err != nil {
+		return nil, err
+	}
+
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: s.options.metrics.Namespace,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	return gauge, s.registerMetric(gauge)
+}
+
+// NewHistogram registers and returns a custom histogram on the service
+// registry. If buckets is nil, prometheus.DefBuckets are used.
+func (s *Service) NewHistogram(name string, help string, buckets []float64, labels ...string) (*prometheus.HistogramVec, error) {
+	if _, err := s.GetMetricsRegistry(); err != nil {
+		return nil, err
+	}
+
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: s.options.metrics.Namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, labels)
+	return histogram, s.registerMetric(histogram)
+}
+
+func (s *Service) registerMetric(collector prometheus.Collector) error {
+	registry, err := s.GetMetricsRegistry()
+	if err != nil {
+		return err
+	}
+	return registry.Register(collector)
+}
+
+// initMetrics must be called once all the other plugins are initialized, as
+// it instruments them.
+func (s *Service) initMetrics() error {
+	if !mustInitializeMetrics(s.options) {
+		return NewNoMetricsOptionsError()
+	}
+
+	options := s.options.metrics
+	s.metrics = prometheus.NewRegistry()
+	s.metrics.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	s.service.Use(newHTTPMetrics(s.metrics, options.Namespace).middleware())
+	s.registerDBMetrics()
+	s.registerRedisMetrics()
+	s.registerInternalDBMetrics()
+
+	handlers := append([]gin.HandlerFunc{}, options.Guards...)
+	handlers = append(handlers, gin.WrapH(promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{})))
+	s.service.GET(options.Path, handlers...)
+	GetLogger().Infof("Metrics exposed on %v", options.Path)
+
+	return nil
+}
+
+func mustInitializeMetrics(options *Options) bool {
+	return options.metrics != nil
+}
+
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+func newHTTPMetrics(registry *prometheus.Registry, namespace string) *httpMetrics {
+	metrics := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of HTTP requests being served.",
+		}),
+	}
+
+	registry.MustRegister(metrics.requests, metrics.latency, metrics.inFlight)
+	return metrics
+}
+
+func (m *httpMetrics) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		panicked := true
+		// Deferred, as this middleware runs after Recovery and a panic
+		// unwinds it before Recovery answers.
+		defer func() {
+			m.inFlight.Dec()
+			status := c.Writer.Status()
+			if panicked {
+				status = http.StatusInternalServerError
+			}
+			m.observe(c, status, time.Since(start))
+		}()
+
+		c.Next()
+		panicked = false
+	}
+}
+
+func (m *httpMetrics) observe(c *gin.Context, status int, latency time.Duration) {
+	// Route template instead of path, to keep labels cardinality low.
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	method := c.Request.Method
+
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(method, route).Observe(latency.Seconds())
+}
+
+func (s *Service) registerDBMetrics() {
+	if s.db == nil {
+		return
+	}
+
+	namespace := s.options.metrics.Namespace
+	stats := func(value func() float64, name string, help string) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      name,
+			Help:      help,
+		}, value)
+	}
+
+	s.metrics.MustRegister(
+		stats(func() float64 { return float64(s.db.Stats().MaxOpenConnections) },
+			"max_open_connections", "Maximum number of open connections to the database."),
+		stats(func() float64 { return float64(s.db.Stats().OpenConnections) },
+			"open_connections", "Number of established connections, in use and idle."),
+		stats(func() float64 { return float64(s.db.Stats().InUse) },
+			"in_use_connections", "Number of connections currently in use."),
+		stats(func() float64 { return float64(s.db.Stats().Idle) },
+			"idle_connections", "Number of idle connections."),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "wait_count_total",
+			Help:      "Total number of connections waited for.",
+		}, func() float64 { return float64(s.db.Stats().WaitCount) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "wait_duration_seconds_total",
+			Help:      "Total time blocked waiting for a new connection.",
+		}, func() float64 { return s.db.Stats().WaitDuration.Seconds() }),
+	)
+}
+
+func (s *Service) registerRedisMetrics() {
+	if s.redisPool == nil {
+		return
+	}
+
+	namespace := s.options.metrics.Namespace
+	s.metrics.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redis",
+			Name:      "active_connections",
+			Help:      "Number of active connections of the redis pool.",
+		}, func() float64 { return float64(s.redisPool.ActiveCount()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redis",
+			Name:      "idle_connections",
+			Help:      "Number of idle connections of the redis pool.",
+		}, func() float64 { return float64(s.redisPool.IdleCount()) }),
+	)
+}
+
+func (s *Service) registerInternalDBMetrics() {
+	if s.badger == nil {
+		return
+	}
+
+	namespace := s.options.metrics.Namespace
+	s.metrics.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "badger",
+			Name:      "lsm_size_bytes",
+			Help:      "Size of the badger LSM tree.",
+		}, func() float64 {
+			lsm, _ := s.badger.Size()
+			return float64(lsm)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "badger",
+			Name:      "vlog_size_bytes",
+			Help:      "Size of the badger value log.",
+		}, func() float64 {
+			_, vlog := s.badger.Size()
+			return float64(vlog)
+		}),
+	)
+}
@@ -3,17 +3,23 @@ package server
 import (
 	"context"
 	"database/sql"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/gomodule/redigo/redis"
+	"github.com/orov-io/BlackBart/env"
+	"github.com/orov-io/BlackBart/reporter"
+	"github.com/orov-io/BlackBart/requestid"
 	"github.com/sirupsen/logrus"
 )
 
-const envKey = "ENV"
 const badgerFlagKey = "ENABLE_BADGER"
 
 // Options store all service configuration options
@@ -25,6 +31,11 @@ type Options struct {
 	gin        *GinOptions
 	service    *ServiceOptions
 	internalDB *InternalDBOptions
+	reporter   reporter.Reporter
+	metrics    *MetricsOptions
+	tracing    *TracingOptions
+	admin      *AdminOptions
+	response   *ResponseOptions
 
 	Context context.Context
 }
@@ -70,6 +81,31 @@ func (o *Options) InternalDB(internalDBOptions *InternalDBOptions) {
 	o.internalDB = internalDBOptions
 }
 
+// Reporter sets the service error reporter
+func (o *Options) Reporter(errorReporter reporter.Reporter) {
+	o.reporter = errorReporter
+}
+
+// Metrics sets the service prometheus metrics configuration
+func (o *Options) Metrics(metricsOptions *MetricsOptions) {
+	o.metrics = metricsOptions
+}
+
+// Tracing sets the service OpenTelemetry tracing configuration
+func (o *Options) Tracing(tracingOptions *TracingOptions) {
+	o.tracing = tracingOptions
+}
+
+// Admin sets the service admin route group configuration
+func (o *Options) Admin(adminOptions *AdminOptions) {
+	o.admin = adminOptions
+}
+
+// Response sets the response package configuration
+func (o *Options) Response(responseOptions *ResponseOptions) {
+	o.response = responseOptions
+}
+
 // WithDefaultOptions attach default configuration to the options struct and returns
 // a pointer with the default configuration options.
 func (o *Options) WithDefaultOptions() *Options {
@@ -81,6 +117,11 @@ func (o *Options) WithDefaultOptions() *Options {
 	o.Firebase(DefaultFirebaseOptions())
 	o.Service(DefaultServiceOptions())
 	o.InternalDB(DefaultInternalDBOptions())
+	o.Reporter(DefaultReporter())
+	o.Metrics(DefaultMetricsOptions())
+	o.Tracing(DefaultTracingOptions())
+	o.Admin(DefaultAdminOptions())
+	o.Response(DefaultResponseOptions())
 
 	return o
 }
@@ -94,7 +135,8 @@ type DBOptions struct {
 	MainDatabase string
 	Password     string
 
-	db *sql.DB
+	db         *sql.DB
+	driverName string
 }
 
 const (
@@ -144,6 +186,15 @@ func (dbo *DBOptions) WithInjectedDB(db *sql.DB) *DBOptions {
 	return dbo
 }
 
+// getDriverName returns the sql driver used to open the database. It is the
+// traced postgres driver when tracing is enabled.
+func (dbo *DBOptions) getDriverName() string {
+	if dbo.driverName == "" {
+		return pluginDatabaseDriver
+	}
+	return dbo.driverName
+}
+
 // GetInjectedDB returns the injected database
 func (dbo *DBOptions) GetInjectedDB() *sql.DB {
 	return dbo.db
@@ -195,16 +246,60 @@ type LoggerOptions struct {
 	Env    string
 	Level  logrus.Level
 	Format logrus.Formatter
+	// LevelSignals makes SIGUSR1 increase the log verbosity and SIGUSR2
+	// restore the configured level. It is opt-in, as the default action of
+	// those signals is to terminate the process.
+	LevelSignals bool
+	// Outputs are the log writers. Logs go to stderr if empty.
+	Outputs []io.Writer
+	// Hooks are added to the logger.
+	Hooks []logrus.Hook
+	// RedactFields are field names, or parts of them, whose values are
+	// replaced with [REDACTED]. See DefaultRedactedFields.
+	RedactFields []string
 }
 
 // DefaultLoggerOptions returns a LoggerOptions filled based on the environment.
+// LOG_LEVEL and LOG_FORMAT override the environment defaults, LOG_OUTPUT sets
+// the outputs, LOG_REDACT_FIELDS (comma separated) extends the default
+// redacted fields and LOG_LEVEL_SIGNALS enables the LevelSignals.
 func DefaultLoggerOptions() *LoggerOptions {
-	env := os.Getenv(envKey)
-	level, format := getDefaultLoggerConfByEnv(env)
+	environment := env.Get()
+	level, format := getDefaultLoggerConfByEnv(environment)
+
+	if envLevel, err := logrus.ParseLevel(os.Getenv(logLevelKey)); err == nil {
+		level = envLevel
+	}
+
+	if envExist(logFormatKey) {
+		envFormat, err := GetFormatter(os.Getenv(logFormatKey))
+		if err != nil {
+			GetLogger().WithError(err).Warn("Ignoring LOG_FORMAT")
+		} else {
+			format = envFormat
+		}
+	}
+
+	outputs, err := GetOutputs(os.Getenv(logOutputKey))
+	if err != nil {
+		GetLogger().WithError(err).Warn("Ignoring LOG_OUTPUT")
+	}
+
+	redact := append([]string{}, DefaultRedactedFields...)
+	if envExist(logRedactKey) {
+		redact = append(redact, strings.Split(os.Getenv(logRedactKey), ",")...)
+	}
+
+	// Don't care about error. If is not nil, we want to keep signals disabled.
+	signals, _ := strconv.ParseBool(os.Getenv(logLevelSignalsKey))
+
 	return &LoggerOptions{
-		Env:    env,
-		Level:  level,
-		Format: format,
+		Env:          environment,
+		Level:        level,
+		Format:       format,
+		LevelSignals: signals,
+		Outputs:      outputs,
+		RedactFields: redact,
 	}
 }
 
@@ -282,8 +377,9 @@ func DefaultGinOptions() *GinOptions {
 
 func getDefaultMiddleware() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
-		gin.Logger(),
-		gin.Recovery(),
+		requestid.Middleware(),
+		AccessLog(),
+		Recovery(),
 	}
 }
 
@@ -291,22 +387,46 @@ func getDefaultCors() cors.Config {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "PUT", "POST", "HEAD", "DELETE", "PATCH", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "authorization", requestid.Header}
+	config.ExposeHeaders = []string{requestid.Header}
 	return config
 }
 
 const (
-	serviceNameKey    = "SERVICE_NAME"
-	serviceVersionKey = "SERVICE_VERSION"
-	servicePathKey    = "SERVICE_BASE_PATH"
+	serviceNameKey      = "SERVICE_NAME"
+	serviceVersionKey   = "SERVICE_VERSION"
+	servicePathKey      = "SERVICE_BASE_PATH"
+	profilerFlagKey     = "ENABLE_PROFILER"
+	profilerModeKey     = "PROFILER_MODE"
+	profilerMutexKey    = "PROFILER_MUTEX"
+	profilerNoAllocKey  = "PROFILER_NO_ALLOC"
+	defaultProfilerPath = "/debug/pprof"
+)
+
+// Profiler modes. See ServiceOptions.
+const (
+	CloudProfiler = "cloud"
+	PprofProfiler = "pprof"
 )
 
 // ServiceOptions stores global service info params.
 type ServiceOptions struct {
-	Name     string
-	Version  string
-	Path     string
+	Name    string
+	Version string
+	Path    string
+	// Profiler enables the profiler.
 	Profiler bool
+	// ProfilerMode is CloudProfiler, the default, or PprofProfiler. The
+	// PprofProfiler endpoints are served on the admin group, so it needs the
+	// admin routes enabled.
+	ProfilerMode string
+	// ProfilerProjectID is the GCP project of the cloud profiler. It is
+	// detected on GCP if empty.
+	ProfilerProjectID string
+	// ProfilerMutex enables mutex profiling on the cloud profiler.
+	ProfilerMutex bool
+	// ProfilerNoAlloc disables allocation profiling on the cloud profiler.
+	ProfilerNoAlloc bool
 }
 
 // NewServiceOptions returns an empty ServiceOptions struct
@@ -315,36 +435,188 @@ func NewServiceOptions() *ServiceOptions {
 }
 
 // DefaultServiceOptions returns a ServiceOptions fills with Name and version
-// founds on the  env variables.
+// founds on the  env variables. The profiler is only enabled if
+// ENABLE_PROFILER is true.
 func DefaultServiceOptions() *ServiceOptions {
-	env := os.Getenv(envKey)
-	profiler := false
-	if env != "local" {
-		profiler = true
-	}
+	profiler, _ := strconv.ParseBool(os.Getenv(profilerFlagKey))
+	mutex, _ := strconv.ParseBool(os.Getenv(profilerMutexKey))
+	noAlloc, _ := strconv.ParseBool(os.Getenv(profilerNoAllocKey))
+
 	return &ServiceOptions{
-		Name:     GetEnvOrDefaultString(serviceNameKey),
-		Version:  GetEnvOrDefaultString(serviceVersionKey),
-		Path:     GetEnvOrDefaultString(servicePathKey),
-		Profiler: profiler,
+		Name:              GetEnvOrDefaultString(serviceNameKey),
+		Version:           GetEnvOrDefaultString(serviceVersionKey),
+		Path:              GetEnvOrDefaultString(servicePathKey),
+		Profiler:          profiler,
+		ProfilerMode:      os.Getenv(profilerModeKey),
+		ProfilerProjectID: os.Getenv(projectKey),
+		ProfilerMutex:     mutex,
+		ProfilerNoAlloc:   noAlloc,
 	}
 }
 
+const (
+	badgerDirKey                   = "BADGER_DIR"
+	badgerValueDirKey              = "BADGER_VALUE_DIR"
+	badgerValueLogFileSizeKey      = "BADGER_VALUE_LOG_FILE_SIZE"
+	badgerValueLogMaxEntriesKey    = "BADGER_VALUE_LOG_MAX_ENTRIES"
+	badgerEncryptionKeyKey         = "BADGER_ENCRYPTION_KEY"
+	badgerEncryptionKeyRotationKey = "BADGER_ENCRYPTION_KEY_ROTATION"
+	badgerMemoryProfileKey         = "BADGER_MEMORY_PROFILE"
+	badgerGCIntervalKey            = "BADGER_GC_INTERVAL"
+	badgerGCDiscardRatioKey        = "BADGER_GC_DISCARD_RATIO"
+)
+
+// Default badger value log garbage collector params.
+const (
+	DefaultBadgerGCInterval     = 5 * time.Minute
+	DefaultBadgerGCDiscardRatio = 0.5
+)
+
+// Badger memory usage profiles. See InternalDBOptions.WithMemoryProfile.
+const (
+	BadgerDefaultMemoryProfile = "default"
+	BadgerLowMemoryProfile     = "low"
+	BadgerHighMemoryProfile    = "high"
+)
+
 // InternalDBOptions stores options to badger, the provided internal database.
 type InternalDBOptions struct {
 	BadgerOptions badger.Options
-	enabled       bool
+	// GCInterval is the period of the value log garbage collector. It only
+	// runs on persistent mode. A zero or negative value disables it.
+	GCInterval time.Duration
+	// GCDiscardRatio is the ratio of discardable data needed to rewrite a
+	// value log file. It must be greater than 0 and less than 1.
+	GCDiscardRatio float64
+	enabled        bool
+}
+
+// NewInternalDBOptions returns a disabled InternalDBOptions struct with an
+// in-memory badger configuration.
+func NewInternalDBOptions() *InternalDBOptions {
+	return &InternalDBOptions{
+		BadgerOptions:  badger.DefaultOptions("").WithInMemory(true),
+		GCInterval:     DefaultBadgerGCInterval,
+		GCDiscardRatio: DefaultBadgerGCDiscardRatio,
+	}
 }
 
-// DefaultInternalDBOptions returns a InternalDBOptions fills with Name and version
-// founds on the  env variables.
+// DefaultInternalDBOptions returns a InternalDBOptions filled with the values
+// founds on the env variables. If BADGER_DIR is not set, badger runs in memory.
 func DefaultInternalDBOptions() *InternalDBOptions {
 	// Don't care about error. If is not nil, we want to set enable to false.
 	enable, _ := strconv.ParseBool(os.Getenv(badgerFlagKey))
-	opt := badger.DefaultOptions("").WithInMemory(true)
+	opt := NewInternalDBOptions().Enabled(enable)
 
-	return &InternalDBOptions{
-		BadgerOptions: opt,
-		enabled:       enable,
+	if envExist(badgerDirKey) {
+		opt.WithDir(os.Getenv(badgerDirKey), os.Getenv(badgerValueDirKey))
+	}
+
+	if size, err := strconv.ParseInt(os.Getenv(badgerValueLogFileSizeKey), 10, 64); err == nil {
+		opt.BadgerOptions = opt.BadgerOptions.WithValueLogFileSize(size)
+	}
+
+	if entries, err := strconv.ParseUint(os.Getenv(badgerValueLogMaxEntriesKey), 10, 32); err == nil {
+		opt.BadgerOptions = opt.BadgerOptions.WithValueLogMaxEntries(uint32(entries))
+	}
+
+	if envExist(badgerEncryptionKeyKey) {
+		rotation, err := time.ParseDuration(os.Getenv(badgerEncryptionKeyRotationKey))
+		if err != nil {
+			rotation = 0
+		}
+		opt.WithEncryption([]byte(os.Getenv(badgerEncryptionKeyKey)), rotation)
+	}
+
+	if envExist(badgerMemoryProfileKey) {
+		opt.WithMemoryProfile(os.Getenv(badgerMemoryProfileKey))
+	}
+
+	if interval, err := time.ParseDuration(os.Getenv(badgerGCIntervalKey)); err == nil {
+		opt.GCInterval = interval
+	}
+
+	if ratio, err := strconv.ParseFloat(os.Getenv(badgerGCDiscardRatioKey), 64); err == nil {
+		opt.GCDiscardRatio = ratio
+	}
+
+	return opt
+}
+
+// Enabled sets if the internal database must be initialized by the service.
+func (ido *InternalDBOptions) Enabled(enabled bool) *InternalDBOptions {
+	ido.enabled = enabled
+	return ido
+}
+
+// IsEnabled returns if the internal database will be initialized by the service.
+func (ido *InternalDBOptions) IsEnabled() bool {
+	return ido.enabled
+}
+
+// WithDir makes badger persist its data on disk. If valueDir is empty, value
+// log files are stored on dir.
+func (ido *InternalDBOptions) WithDir(dir string, valueDir string) *InternalDBOptions {
+	if valueDir == "" {
+		valueDir = dir
+	}
+	ido.BadgerOptions = ido.BadgerOptions.
+		WithInMemory(false).
+		WithDir(dir).
+		WithValueDir(valueDir)
+	return ido
+}
+
+// WithValueLog sets the max size in bytes and the max number of entries of
+// each value log file. Zero values keep the current configuration.
+func (ido *InternalDBOptions) WithValueLog(fileSize int64, maxEntries uint32) *InternalDBOptions {
+	if fileSize > 0 {
+		ido.BadgerOptions = ido.BadgerOptions.WithValueLogFileSize(fileSize)
+	}
+	if maxEntries > 0 {
+		ido.BadgerOptions = ido.BadgerOptions.WithValueLogMaxEntries(maxEntries)
+	}
+	return ido
+}
+
+// WithEncryption enables encryption at rest. The key must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256. If rotation is zero, badger
+// default rotation duration is kept.
+func (ido *InternalDBOptions) WithEncryption(key []byte, rotation time.Duration) *InternalDBOptions {
+	ido.BadgerOptions = ido.BadgerOptions.WithEncryptionKey(key)
+	if rotation > 0 {
+		ido.BadgerOptions = ido.BadgerOptions.WithEncryptionKeyRotationDuration(rotation)
+	}
+	return ido
+}
+
+// WithMemoryProfile tunes badger memory usage. Use one of the Badger*MemoryProfile
+// constants. Unknown profiles keep the current configuration.
+func (ido *InternalDBOptions) WithMemoryProfile(profile string) *InternalDBOptions {
+	switch profile {
+	case BadgerLowMemoryProfile:
+		ido.BadgerOptions = ido.BadgerOptions.
+			WithTableLoadingMode(options.FileIO).
+			WithValueLogLoadingMode(options.FileIO).
+			WithNumMemtables(1).
+			WithMaxTableSize(16 << 20).
+			WithNumLevelZeroTables(1).
+			WithNumLevelZeroTablesStall(2)
+	case BadgerHighMemoryProfile:
+		ido.BadgerOptions = ido.BadgerOptions.
+			WithTableLoadingMode(options.LoadToRAM).
+			WithValueLogLoadingMode(options.MemoryMap)
+	case BadgerDefaultMemoryProfile:
+		defaults := badger.DefaultOptions("")
+		ido.BadgerOptions = ido.BadgerOptions.
+			WithTableLoadingMode(defaults.TableLoadingMode).
+			WithValueLogLoadingMode(defaults.ValueLogLoadingMode).
+			WithNumMemtables(defaults.NumMemtables).
+			WithMaxTableSize(defaults.MaxTableSize).
+			WithNumLevelZeroTables(defaults.NumLevelZeroTables).
+			WithNumLevelZeroTablesStall(defaults.NumLevelZeroTablesStall)
+	default:
+		GetLogger().Warnf("Unknown badger memory profile %v. Skipping it", profile)
 	}
+	return ido
 }
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"cloud.google.com/go/profiler"
+	"github.com/gin-gonic/gin"
+)
+
+// pprofProfiles are the runtime profiles served by pprof.Handler
+var pprofProfiles = []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"}
+
+// initProfiler starts the configured profiler. Profiler errors must not stop
+// the service, so the caller only logs them.
+func (s *Service) initProfiler() error {
+	if !mustInitializeProfiler(s.options) {
+		return NewNoProfilerOptionsError()
+	}
+
+	options := s.options.service
+	switch options.ProfilerMode {
+	case PprofProfiler:
+		return usePprof(s.options)
+	case CloudProfiler, "":
+		return startCloudProfiler(options)
+	}
+
+	return NewUnknownProfilerModeError(options.ProfilerMode)
+}
+
+func mustInitializeProfiler(options *Options) bool {
+	return options.service != nil && options.service.Profiler
+}
+
+func startCloudProfiler(options *ServiceOptions) error {
+	err := profiler.Start(profiler.Config{
+		Service:          options.Name,
+		ServiceVersion:   options.Version,
+		ProjectID:        options.ProfilerProjectID,
+		MutexProfiling:   options.ProfilerMutex,
+		NoAllocProfiling: options.ProfilerNoAlloc,
+	})
+
+	if err == nil {
+		GetLogger().Info("Cloud profiler started")
+	}
+	return err
+}
+
+// usePprof checks the net/http/pprof endpoints are served. They are only
+// mounted on the admin group, behind its authenticator, so pprof mode needs
+// the admin routes enabled.
+func usePprof(options *Options) error {
+	if !mustInitializeAdmin(options) {
+		return NewPprofWithoutAdminError()
+	}
+
+	GetLogger().Infof("pprof endpoints served on %v%v", options.admin.Path, defaultProfilerPath)
+	return nil
+}
+
+func addPprofRoutes(group *gin.RouterGroup) {
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, profile := range pprofProfiles {
+		group.GET("/"+profile, gin.WrapH(pprof.Handler(profile)))
+	}
+}
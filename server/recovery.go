@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/response"
+)
+
+const stackField = "stack"
+
+// PanicHook is called by the Recovery middleware with every recovered panic.
+// Use it to send panics to an error reporter.
+type PanicHook func(c *gin.Context, recovered interface{}, stack []byte)
+
+// Recovery returns a middleware that recovers from panics, logs them with their
+// stack trace and the request context, and sends a standard 500 response. It
+// replaces gin.Recovery() on the default middleware.
+func Recovery(hooks ...PanicHook) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			stack := debug.Stack()
+			Log(c).WithField(stackField, string(stack)).Errorf("panic recovered: %v", recovered)
+
+			reportPanic(c, recovered, stack)
+			for _, hook := range hooks {
+				hook(c, recovered, stack)
+			}
+
+			if isBrokenPipe(recovered) || c.Writer.Written() {
+				// Client is gone or headers are already sent.
+				c.Abort()
+				return
+			}
+
+			response.SendInternalError(c, panicToError(recovered))
+		}()
+
+		c.Next()
+	}
+}
+
+func panicToError(recovered interface{}) error {
+	if err, ok := recovered.(error); ok {
+		return err
+	}
+	return fmt.Errorf("panic: %v", recovered)
+}
+
+func isBrokenPipe(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+
+	var opErr *net.OpError
+	var syscallErr *os.SyscallError
+	if errors.As(err, &opErr) && errors.As(opErr.Err, &syscallErr) {
+		message := strings.ToLower(syscallErr.Error())
+		return strings.Contains(message, "broken pipe") ||
+			strings.Contains(message, "connection reset by peer")
+	}
+	return false
+}
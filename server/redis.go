@@ -1,6 +1,8 @@
 package server
 
 import (
+	"context"
+
 	"github.com/gomodule/redigo/redis"
 )
 
@@ -55,6 +57,12 @@ func (s *Service) initRedisPool() error {
 		s.redisPool, err = initializeRedisPoolFromOptions(s.options.redis)
 	})
 
+	if err == nil && s.redisPool != nil {
+		s.OnClose("redis", func(ctx context.Context) error {
+			return s.redisPool.Close()
+		})
+	}
+
 	return err
 }
 
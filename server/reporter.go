@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/orov-io/BlackBart/reporter"
+	"github.com/orov-io/BlackBart/response"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errorReporterKey   = "ERROR_REPORTER"
+	errorReporterCloud = "cloud"
+	errorReporterLocal = "local"
+	recoveredKey       = "blackbart_recovered"
+)
+
+// DefaultReporter returns the error reporter selected by the ERROR_REPORTER
+// env variable: "cloud" writes Cloud Error Reporting entries to stdout and
+// "local" writes human readable reports to stderr. Returns nil otherwise.
+func DefaultReporter() reporter.Reporter {
+	switch os.Getenv(errorReporterKey) {
+	case errorReporterCloud:
+		return reporter.NewCloudErrorReporting(os.Stdout)
+	case errorReporterLocal:
+		return reporter.NewLocal(os.Stderr)
+	}
+	return nil
+}
+
+func (s *Service) initReporter() {
+	if s.options.reporter == nil {
+		GetLogger().Debug("Error reporter not provided. Skipping error reporting")
+		return
+	}
+
+	s.reporter = s.options.reporter
+	response.SetServerErrorHook(s.reportServerError)
+}
+
+// GetReporter returns the error reporter attached to the service, if any.
+func (s *Service) GetReporter() reporter.Reporter {
+	return s.reporter
+}
+
+func (s *Service) reportServerError(c *gin.Context, err error, status int, traceID string) {
+	// Panics are already reported, with their own stack, by Recovery.
+	if c.GetBool(recoveredKey) {
+		return
+	}
+
+	event := s.newReportEvent(c, err, debug.Stack())
+	event.Status = status
+	event.TraceID = traceID
+	s.reporter.Report(event)
+}
+
+// reportPanic sends a recovered panic to the service reporter, if any.
+func reportPanic(c *gin.Context, recovered interface{}, stack []byte) {
+	c.Set(recoveredKey, true)
+	if instance == nil || instance.reporter == nil {
+		return
+	}
+
+	event := instance.newReportEvent(c, panicToError(recovered), stack)
+	event.Panic = true
+	event.Status = http.StatusInternalServerError
+	instance.reporter.Report(event)
+}
+
+func (s *Service) newReportEvent(c *gin.Context, err error, stack []byte) *reporter.Event {
+	event := &reporter.Event{
+		Time:     time.Now(),
+		Err:      err,
+		Stack:    stack,
+		Request:  redactRequest(c.Request),
+		RemoteIP: c.ClientIP(),
+		User:     c.GetString(UIDKey),
+		Trace:    getRequestTrace(c),
+	}
+
+	if s.options != nil && s.options.service != nil {
+		event.Service = s.options.service.Name
+		event.Version = s.options.service.Version
+	}
+
+	return event
+}
+
+// redactRequest returns a shallow copy of the request with the access_token
+// masked on its URL, so the reporters don't send the token.
+func redactRequest(req *http.Request) *http.Request {
+	requestURL := redactURL(req.URL)
+	if requestURL == req.URL {
+		return req
+	}
+
+	redacted := *req
+	redacted.URL = requestURL
+	return &redacted
+}
+
+// getRequestTrace returns the Cloud Trace resource name of the request span or,
+// if it is not traced, of the X-Cloud-Trace-Context header.
+func getRequestTrace(c *gin.Context) string {
+	spanContext := trace.SpanContextFromContext(c.Request.Context())
+	if spanContext.IsValid() {
+		return cloudTraceName(spanContext.TraceID().String())
+	}
+
+	return getCloudTrace(c)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/orov-io/BlackBart/response"
+)
+
+const (
+	responseProblemDetailsKey = "RESPONSE_PROBLEM_DETAILS"
+	responseDefaultFormatKey  = "RESPONSE_DEFAULT_FORMAT"
+	responseEnvelopeKey       = "RESPONSE_ENVELOPE"
+	responseHideErrorsKey     = "RESPONSE_HIDE_ERRORS"
+)
+
+// ResponseOptions stores the configuration of the response package.
+type ResponseOptions struct {
+	// ProblemDetails sends error responses as RFC 7807
+	// application/problem+json objects.
+	ProblemDetails bool
+	// DefaultFormat is the format sent when the request accepts any format.
+	// One of json, xml, yaml, msgpack or csv, or its media type.
+	DefaultFormat string
+	// Envelope wraps success and error responses on a
+	// {"data", "meta", "errors"} envelope.
+	Envelope bool
+	// HidePolicy decides which error responses hide their errors: always,
+	// never or 5xx. Defaults to always on production and never otherwise.
+	HidePolicy response.HidePolicy
+	// SafeErrors are never hidden. Errors wrapping them are matched with
+	// errors.Is.
+	SafeErrors []error
+}
+
+// NewResponseOptions returns a ResponseOptions struct with the default
+// response formats.
+func NewResponseOptions() *ResponseOptions {
+	return &ResponseOptions{
+		HidePolicy: response.DefaultHidePolicy(),
+	}
+}
+
+// DefaultResponseOptions returns a ResponseOptions filled with the
+// RESPONSE_PROBLEM_DETAILS, RESPONSE_DEFAULT_FORMAT, RESPONSE_ENVELOPE and
+// RESPONSE_HIDE_ERRORS env variables.
+func DefaultResponseOptions() *ResponseOptions {
+	options := NewResponseOptions()
+	options.ProblemDetails, _ = strconv.ParseBool(os.Getenv(responseProblemDetailsKey))
+	options.DefaultFormat = os.Getenv(responseDefaultFormatKey)
+	options.Envelope, _ = strconv.ParseBool(os.Getenv(responseEnvelopeKey))
+	if envExist(responseHideErrorsKey) {
+		policy, err := response.ParseHidePolicy(os.Getenv(responseHideErrorsKey))
+		if err != nil {
+			GetLogger().WithError(err).Warn("Ignoring RESPONSE_HIDE_ERRORS")
+		} else {
+			options.HidePolicy = policy
+		}
+	}
+	return options
+}
+
+// initResponse configures the response package. It is always available, so
+// the defaults are kept when no options are provided.
+func (s *Service) initResponse() {
+	options := s.options.response
+	if options == nil {
+		return
+	}
+
+	response.SetProblemDetails(options.ProblemDetails)
+	response.SetEnvelope(options.Envelope)
+	if options.HidePolicy != "" {
+		response.SetHidePolicy(options.HidePolicy)
+	}
+	response.AllowErrors(options.SafeErrors...)
+	if options.DefaultFormat != "" {
+		err := response.SetDefaultFormat(options.DefaultFormat)
+		if err != nil {
+			GetLogger().WithError(err).Warn("Can't set the default response format. Using JSON")
+		}
+	}
+}
@@ -3,10 +3,12 @@ package server
 import (
 	"context"
 	"database/sql"
+	"io"
 	"net/http"
+	"os"
 	"sync"
+	"sync/atomic"
 
-	"cloud.google.com/go/profiler"
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
 	badger "github.com/dgraph-io/badger/v2"
@@ -14,6 +16,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gomodule/redigo/redis"
 	"github.com/jmoiron/sqlx"
+	"github.com/orov-io/BlackBart/env"
+	"github.com/orov-io/BlackBart/reporter"
+	"github.com/orov-io/BlackBart/response"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/appengine"
 )
@@ -22,7 +28,7 @@ import (
 const (
 	ReleaseMode = gin.ReleaseMode
 	DebugMode   = gin.DebugMode
-	Production  = "PROD"
+	Production  = env.Production
 )
 
 var onceService sync.Once
@@ -41,6 +47,25 @@ type Service struct {
 	log       *logrus.Logger
 	firebase  *firebase.App
 	badger    *badger.DB
+	reporter  reporter.Reporter
+	metrics   *prometheus.Registry
+
+	internalDBGCStop chan struct{}
+	internalDBGCDone chan struct{}
+	serving          int32
+
+	plugins map[string]*PluginStatus
+	admin   *gin.RouterGroup
+
+	firebaseMutex sync.RWMutex
+
+	onceWebSocketHub sync.Once
+	webSocketHub     *Hub
+
+	closeMutex sync.Mutex
+	closers    []closer
+	closed     bool
+	closeErr   error
 }
 
 // Init initializes a service if there are no other service initialized
@@ -79,12 +104,26 @@ func (s *Service) init() error {
 	var err error
 
 	s.initLogger()
+	s.trackPlugin("logger", nil, false)
+	s.initResponse()
+	s.initReporter()
+	s.trackPlugin("reporter", nil, s.reporter == nil)
+
+	err = s.initTracing()
+	if err != nil && !IsNoTracingOptionsError(err) {
+		GetLogger().WithError(err).Fatal("Can't initialize tracing")
+	} else if IsNoTracingOptionsError(err) {
+		GetLogger().Debug("Tracing config not provided. Skipping tracing initialization")
+	}
+	s.trackPlugin("tracing", err, IsNoTracingOptionsError(err))
+
 	err = s.initDB()
 	if err != nil && !IsNoDatabaseOptionsError(err) {
 		GetLogger().WithError(err).Fatal("Can't connect to provided database")
 	} else if IsNoDatabaseOptionsError(err) {
 		GetLogger().Debug("Database config not provided. Skipping db initialization")
 	}
+	s.trackPlugin("database", err, IsNoDatabaseOptionsError(err))
 
 	err = s.initAuth()
 	if err != nil && !IsNoFirebaseOptionsError(err) {
@@ -92,6 +131,7 @@ func (s *Service) init() error {
 	} else if IsNoFirebaseOptionsError(err) {
 		GetLogger().Debug("Firebase config not provided. Skipping auth initialization")
 	}
+	s.trackPlugin("firebase", err, IsNoFirebaseOptionsError(err))
 
 	err = s.initRouter()
 	if err != nil && !IsNoGinOptionsError(err) {
@@ -99,6 +139,8 @@ func (s *Service) init() error {
 	} else if IsNoGinOptionsError(err) {
 		GetLogger().Debug("Gin config not provided. Default router initialized")
 	}
+	// A default router is initialized even without options
+	s.trackPlugin("router", nil, false)
 
 	err = s.initRedisPool()
 	if err != nil && !IsNoRedisOptionsError(err) {
@@ -106,8 +148,15 @@ func (s *Service) init() error {
 	} else if IsNoRedisOptionsError(err) {
 		GetLogger().Debug("Redis config not provided. Skipping redis initialization")
 	}
+	s.trackPlugin("redis", err, IsNoRedisOptionsError(err))
 
-	s.initProfiler()
+	err = s.initProfiler()
+	if err != nil && !IsNoProfilerOptionsError(err) {
+		GetLogger().WithError(err).Warn("Can't start the profiler. Continuing without it")
+	} else if IsNoProfilerOptionsError(err) {
+		GetLogger().Debug("Profiler not enabled. Skipping profiler initialization")
+	}
+	s.trackPlugin("profiler", err, IsNoProfilerOptionsError(err))
 
 	err = s.initInternalDB()
 	GetLogger().Debug("Initializing badger")
@@ -116,6 +165,23 @@ func (s *Service) init() error {
 	} else if IsNoInternalDatabaseOptionsError(err) {
 		GetLogger().Debug("InternalDB not required. Skipping badger initialization")
 	}
+	s.trackPlugin("internal database", err, IsNoInternalDatabaseOptionsError(err))
+
+	err = s.initMetrics()
+	if err != nil && !IsNoMetricsOptionsError(err) {
+		GetLogger().WithError(err).Fatal("Can't initialize metrics")
+	} else if IsNoMetricsOptionsError(err) {
+		GetLogger().Debug("Metrics config not provided. Skipping metrics initialization")
+	}
+	s.trackPlugin("metrics", err, IsNoMetricsOptionsError(err))
+
+	err = s.initAdmin()
+	if err != nil && !IsNoAdminOptionsError(err) {
+		GetLogger().WithError(err).Fatal("Can't mount the admin routes")
+	} else if IsNoAdminOptionsError(err) {
+		GetLogger().Debug("Admin config not provided. Skipping admin routes")
+	}
+	s.trackPlugin("admin", err, IsNoAdminOptionsError(err))
 
 	return nil
 }
@@ -127,18 +193,34 @@ func (s *Service) initRouter() error {
 	}
 	s.service.Use(s.options.gin.Middleware...)
 	s.service.Use(cors.New(s.options.gin.Cors))
-	return nil
-}
-
-func (s *Service) initProfiler() {
-	if s.options.service.Profiler {
-		profiler.Start(profiler.Config{})
+	if mustInitializeTracing(s.options) {
+		s.service.Use(Tracing())
 	}
+	return nil
 }
 
 func (s *Service) initLogger() {
 	setLogger(s.options.logger)
 	s.log = GetLogger()
+	response.SetLogger(s.log)
+	if s.options.logger.LevelSignals {
+		s.handleLogLevelSignals()
+	}
+	s.closeLoggerOutputs()
+}
+
+// closeLoggerOutputs registers the closable log outputs, as files or syslog,
+// to be closed by CloseAll.
+func (s *Service) closeLoggerOutputs() {
+	for _, output := range s.options.logger.Outputs {
+		closer, ok := output.(io.Closer)
+		if !ok || output == os.Stdout || output == os.Stderr {
+			continue
+		}
+		s.OnClose("log output", func(ctx context.Context) error {
+			return closer.Close()
+		})
+	}
 }
 
 // GetService returns the service if initialized.
@@ -193,14 +275,17 @@ func (s *Service) GetInternalDB() (*badger.DB, error) {
 
 // GetAuthClient returns a instance of the attached auth client
 func (s *Service) GetAuthClient() (*auth.Client, error) {
-	if s.firebase == nil {
-		return nil, FirebaseNotAlreadyInitializedError()
+	app, err := s.GetFirebaseApp()
+	if err != nil {
+		return nil, err
 	}
-	return s.firebase.Auth(context.Background())
+	return app.Auth(context.Background())
 }
 
 // GetFirebaseApp returns a instance of the attached firebase app
 func (s *Service) GetFirebaseApp() (*firebase.App, error) {
+	s.firebaseMutex.RLock()
+	defer s.firebaseMutex.RUnlock()
 	if s.firebase == nil {
 		return nil, FirebaseNotAlreadyInitializedError()
 	}
@@ -241,6 +326,7 @@ func initService(options *Options) (*Service, error) {
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (s *Service) Run(addr ...string) error {
 	GetLogger().Infof("Running with gin router")
+	atomic.StoreInt32(&s.serving, 1)
 	return s.service.Run(addr...)
 }
 
@@ -249,6 +335,7 @@ func (s *Service) Run(addr ...string) error {
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (s *Service) RunAppEngine() error {
 	GetLogger().Infof("Running on appengine env")
+	atomic.StoreInt32(&s.serving, 1)
 	http.Handle("/", s.service)
 	appengine.Main()
 	return nil
@@ -263,26 +350,3 @@ func (s *Service) SetMode(mode string) {
 func (s *Service) Group(relativePath string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
 	return s.service.Group(relativePath, handlers...)
 }
-
-// CloseAll closes all opened database connections
-func (s *Service) CloseAll() error {
-	if s.dbx != nil {
-		if err := s.dbx.Close(); err != nil {
-			return err
-		}
-	}
-
-	if s.redisPool != nil {
-		if err := s.redisPool.Close(); err != nil {
-			return err
-		}
-	}
-
-	if s.badger != nil {
-		if err := s.db.Close(); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
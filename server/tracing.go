@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracingExporterKey    = "TRACING_EXPORTER"
+	tracingSampleRatioKey = "TRACING_SAMPLE_RATIO"
+
+	tracerName   = "github.com/orov-io/BlackBart/server"
+	traceIDField = "trace_id"
+	spanIDField  = "logging.googleapis.com/spanId"
+)
+
+// Tracing exporters accepted by TracingOptions.
+const (
+	OTLPExporter   = "otlp"
+	StdoutExporter = "stdout"
+	NoopExporter   = "none"
+)
+
+// TracingOptions stores the OpenTelemetry tracing configuration.
+type TracingOptions struct {
+	// Exporter is one of OTLPExporter, StdoutExporter or NoopExporter. The
+	// OTLP exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+	// env variables.
+	Exporter string
+	// SampleRatio is the ratio of new traces to sample. Requests with a
+	// sampled parent are always sampled.
+	SampleRatio float64
+}
+
+// NewTracingOptions returns a TracingOptions struct that samples all traces.
+func NewTracingOptions(exporter string) *TracingOptions {
+	return &TracingOptions{
+		Exporter:    exporter,
+		SampleRatio: 1,
+	}
+}
+
+// DefaultTracingOptions returns a TracingOptions filled with the
+// TRACING_EXPORTER and TRACING_SAMPLE_RATIO env variables. Returns nil if
+// TRACING_EXPORTER is not set, as tracing is opt-in.
+func DefaultTracingOptions() *TracingOptions {
+	if !envExist(tracingExporterKey) {
+		return nil
+	}
+
+	options := NewTracingOptions(os.Getenv(tracingExporterKey))
+	if ratio, err := strconv.ParseFloat(os.Getenv(tracingSampleRatioKey), 64); err == nil {
+		options.SampleRatio = ratio
+	}
+	return options
+}
+
+func (s *Service) initTracing() error {
+	if !mustInitializeTracing(s.options) {
+		return NewNoTracingOptionsError()
+	}
+
+	exporter, err := newSpanExporter(s.options.tracing.Exporter)
+	if err != nil {
+		return err
+	}
+
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(s.getTracingResource()),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(s.options.tracing.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOptions...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		CloudTraceContext{},
+	))
+	s.OnClose("tracing", provider.Shutdown)
+
+	GetLogger().Infof("Tracing configured with %v exporter", s.options.tracing.Exporter)
+	return nil
+}
+
+func mustInitializeTracing(options *Options) bool {
+	return options.tracing != nil
+}
+
+func newSpanExporter(exporter string) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case OTLPExporter:
+		return otlptracehttp.New(context.Background())
+	case StdoutExporter:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case NoopExporter:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Unknown tracing exporter %v", exporter)
+}
+
+func (s *Service) getTracingResource() *resource.Resource {
+	attributes := make([]attribute.KeyValue, 0, 2)
+	if s.options.service != nil {
+		attributes = append(attributes,
+			attribute.String("service.name", s.options.service.Name),
+			attribute.String("service.version", s.options.service.Version),
+		)
+	}
+	return resource.NewSchemaless(attributes...)
+}
+
+// Tracing returns a middleware that extracts the W3C traceparent or the
+// X-Cloud-Trace-Context headers and starts a server span for the request.
+// It is added to the router when tracing is enabled.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", c.Request.URL.Path),
+				attribute.String("http.user_agent", c.Request.UserAgent()),
+				attribute.String("net.sock.peer.addr", c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if uid := c.GetString(UIDKey); uid != "" {
+			span.SetAttributes(attribute.String("enduser.id", uid))
+		}
+	}
+}
+
+// traceHook links the active span of the entry context to the log entry.
+type traceHook struct{}
+
+func (h *traceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *traceHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	entry.Data[traceIDField] = spanContext.TraceID().String()
+	entry.Data[spanIDField] = spanContext.SpanID().String()
+	if name := cloudTraceName(spanContext.TraceID().String()); name != "" {
+		entry.Data[traceField] = name
+	}
+	return nil
+}
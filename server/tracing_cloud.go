@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CloudTraceContext propagates trace context with the GCP
+// X-Cloud-Trace-Context header: TRACE_ID/SPAN_ID;o=OPTIONS, where SPAN_ID is
+// a decimal number.
+type CloudTraceContext struct{}
+
+var _ propagation.TextMapPropagator = CloudTraceContext{}
+
+// Inject implements propagation.TextMapPropagator
+func (CloudTraceContext) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return
+	}
+
+	spanID := spanContext.SpanID()
+	sampled := 0
+	if spanContext.IsSampled() {
+		sampled = 1
+	}
+	carrier.Set(traceHeader, fmt.Sprintf("%v/%d;o=%d",
+		spanContext.TraceID(),
+		binary.BigEndian.Uint64(spanID[:]),
+		sampled,
+	))
+}
+
+// Extract implements propagation.TextMapPropagator. The W3C traceparent header
+// takes precedence if both are sent.
+func (CloudTraceContext) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	header := carrier.Get(traceHeader)
+	if header == "" {
+		return ctx
+	}
+
+	parts := strings.SplitN(header, "/", 2)
+	traceID, err := trace.TraceIDFromHex(parts[0])
+	if err != nil || len(parts) < 2 {
+		return ctx
+	}
+
+	spanAndOptions := strings.SplitN(parts[1], ";", 2)
+	decimalSpanID, err := strconv.ParseUint(spanAndOptions[0], 10, 64)
+	if err != nil {
+		return ctx
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], decimalSpanID)
+
+	var flags trace.TraceFlags
+	if len(spanAndOptions) == 2 && spanAndOptions[1] == "o=1" {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+// Fields implements propagation.TextMapPropagator
+func (CloudTraceContext) Fields() []string {
+	return []string{traceHeader}
+}
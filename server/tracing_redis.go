@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetRedisConnContext returns a connection from the redis pool whose commands
+// are traced as children of the span carried by ctx.
+func GetRedisConnContext(ctx context.Context) (redis.Conn, error) {
+	pool, err := GetRedisPool()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedRedisConn{Conn: pool.Get(), ctx: ctx}, nil
+}
+
+type tracedRedisConn struct {
+	redis.Conn
+	ctx context.Context
+}
+
+func (c *tracedRedisConn) Do(command string, args ...interface{}) (interface{}, error) {
+	// An empty command flushes pipelined commands. It is not traced.
+	if command == "" || !trace.SpanContextFromContext(c.ctx).IsValid() {
+		return c.Conn.Do(command, args...)
+	}
+
+	_, span := otel.Tracer(tracerName).Start(c.ctx, "redis."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", command),
+		),
+	)
+	defer span.End()
+
+	reply, err := c.Conn.Do(command, args...)
+	if err != nil && err != redis.ErrNil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
@@ -0,0 +1,481 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	webSocketWriteWait      = 10 * time.Second
+	webSocketPongWait       = 60 * time.Second
+	webSocketPingPeriod     = webSocketPongWait * 9 / 10
+	webSocketSendBuffer     = 256
+	webSocketMaxMessageSize = 1 << 20
+	webSocketResubscribe    = 5 * time.Second
+	webSocketChannel        = "blackbart:websocket"
+)
+
+// WebSocketHandler handles an upgraded connection. The connection is closed
+// when the handler returns.
+type WebSocketHandler func(conn *WebSocketConn)
+
+// WebSocket mounts a WebSocket endpoint on the path. Upgrade requests are
+// authenticated by the guards or, if none are provided, by FirebaseAuth.
+// Connections join the service hub, so they can receive broadcasts, and are
+// closed on CloseAll.
+func (s *Service) WebSocket(path string, handler WebSocketHandler, guards ...gin.HandlerFunc) error {
+	if len(guards) == 0 {
+		if _, err := s.GetFirebaseApp(); err != nil {
+			return NewUnauthenticatedWebSocketError(path)
+		}
+		guards = []gin.HandlerFunc{s.FirebaseAuth()}
+	}
+
+	hub := s.GetWebSocketHub()
+	upgrader := &websocket.Upgrader{CheckOrigin: s.checkWebSocketOrigin}
+	handlers := append(append([]gin.HandlerFunc{}, guards...), func(c *gin.Context) {
+		socket, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader already sent the error response
+			Log(c).WithError(err).Debug("Can't upgrade to WebSocket")
+			c.Abort()
+			return
+		}
+
+		conn := hub.register(socket, c)
+		defer hub.unregister(conn)
+		go conn.writeLoop()
+		handler(conn)
+	})
+
+	s.service.GET(path, handlers...)
+	return nil
+}
+
+// checkWebSocketOrigin allows the origins allowed by the CORS config. Same
+// origin requests and requests with no Origin header are always allowed.
+func (s *Service) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "http://"+r.Host || origin == "https://"+r.Host {
+		return true
+	}
+	if s.options.gin == nil {
+		return false
+	}
+
+	cors := s.options.gin.Cors
+	if cors.AllowAllOrigins {
+		return true
+	}
+	for _, allowed := range cors.AllowOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return cors.AllowOriginFunc != nil && cors.AllowOriginFunc(origin)
+}
+
+// GetWebSocketHub returns the hub of the service WebSocket connections. It is
+// created on the first call. If redis is initialized, broadcasts are fanned
+// out to all the service instances through redis pub/sub.
+func (s *Service) GetWebSocketHub() *Hub {
+	s.onceWebSocketHub.Do(func() {
+		s.webSocketHub = newHub(s.redisPool)
+		s.webSocketHub.subscribe()
+		s.OnClose("websocket", s.webSocketHub.close)
+	})
+	return s.webSocketHub
+}
+
+// Hub tracks the open WebSocket connections and their rooms.
+type Hub struct {
+	id    string
+	pool  *redis.Pool
+	mutex sync.RWMutex
+	conns map[*WebSocketConn]struct{}
+	rooms map[string]map[*WebSocketConn]struct{}
+
+	closed     bool
+	subscriber *redis.PubSubConn
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// hubMessage is the payload published on redis to fan out broadcasts.
+type hubMessage struct {
+	Origin string `json:"origin"`
+	Room   string `json:"room,omitempty"`
+	Data   []byte `json:"data"`
+}
+
+func newHub(pool *redis.Pool) *Hub {
+	return &Hub{
+		id:    uuid.New().String(),
+		pool:  pool,
+		conns: make(map[*WebSocketConn]struct{}),
+		rooms: make(map[string]map[*WebSocketConn]struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// Broadcast sends the message to all the connections.
+func (h *Hub) Broadcast(message []byte) error {
+	return h.BroadcastRoom("", message)
+}
+
+// BroadcastRoom sends the message to the connections joined to the room. An
+// empty room sends it to all the connections.
+func (h *Hub) BroadcastRoom(room string, message []byte) error {
+	h.deliver(room, message)
+	return h.publish(room, message)
+}
+
+// BroadcastJSON sends the JSON encoding of v to the connections joined to the
+// room, or to all of them if room is empty.
+func (h *Hub) BroadcastJSON(room string, v interface{}) error {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return h.BroadcastRoom(room, message)
+}
+
+// Count returns the number of connections of this instance.
+func (h *Hub) Count() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.conns)
+}
+
+func (h *Hub) register(socket *websocket.Conn, c *gin.Context) *WebSocketConn {
+	conn := &WebSocketConn{
+		socket: socket,
+		hub:    h,
+		send:   make(chan []byte, webSocketSendBuffer),
+		ctx:    c,
+		rooms:  make(map[string]struct{}),
+	}
+
+	// The client must answer the pings before the read deadline
+	socket.SetReadLimit(webSocketMaxMessageSize)
+	socket.SetReadDeadline(time.Now().Add(webSocketPongWait))
+	socket.SetPongHandler(func(string) error {
+		return socket.SetReadDeadline(time.Now().Add(webSocketPongWait))
+	})
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		close(conn.send)
+		return conn
+	}
+	h.conns[conn] = struct{}{}
+	return conn
+}
+
+func (h *Hub) unregister(conn *WebSocketConn) {
+	h.mutex.Lock()
+	if _, ok := h.conns[conn]; ok {
+		delete(h.conns, conn)
+		for room := range conn.rooms {
+			h.leave(conn, room)
+		}
+		close(conn.send)
+	}
+	h.mutex.Unlock()
+
+	<-conn.writerDone()
+	conn.socket.Close()
+}
+
+func (h *Hub) join(conn *WebSocketConn, room string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.conns[conn]; !ok {
+		return
+	}
+
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*WebSocketConn]struct{})
+	}
+	h.rooms[room][conn] = struct{}{}
+	conn.rooms[room] = struct{}{}
+}
+
+// leave must be called with the hub lock held.
+func (h *Hub) leave(conn *WebSocketConn, room string) {
+	delete(conn.rooms, room)
+	delete(h.rooms[room], conn)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// deliver sends the message to the local connections of the room.
+// Connections that can't keep up are dropped.
+func (h *Hub) deliver(room string, message []byte) {
+	h.mutex.RLock()
+	targets := h.conns
+	if room != "" {
+		targets = h.rooms[room]
+	}
+
+	slow := make([]*WebSocketConn, 0)
+	for conn := range targets {
+		select {
+		case conn.send <- message:
+		default:
+			slow = append(slow, conn)
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, conn := range slow {
+		GetLogger().Warn("Dropping a slow WebSocket connection")
+		conn.socket.Close()
+	}
+}
+
+func (h *Hub) publish(room string, message []byte) error {
+	if h.pool == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(&hubMessage{Origin: h.id, Room: room, Data: message})
+	if err != nil {
+		return err
+	}
+
+	conn := h.pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PUBLISH", webSocketChannel, payload)
+	return err
+}
+
+// subscribe delivers the broadcasts of other instances, resubscribing if the
+// redis connection is lost, until the hub is closed.
+func (h *Hub) subscribe() {
+	if h.pool == nil {
+		close(h.done)
+		return
+	}
+
+	go func() {
+		defer close(h.done)
+		for {
+			err := h.receive()
+			select {
+			case <-h.stop:
+				return
+			default:
+			}
+
+			GetLogger().WithError(err).Warn("WebSocket redis subscription lost. Resubscribing")
+			select {
+			case <-h.stop:
+				return
+			case <-time.After(webSocketResubscribe):
+			}
+		}
+	}()
+}
+
+func (h *Hub) receive() error {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return nil
+	}
+	subscriber := &redis.PubSubConn{Conn: h.pool.Get()}
+	h.subscriber = subscriber
+	h.mutex.Unlock()
+	defer subscriber.Close()
+
+	err := subscriber.Subscribe(webSocketChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		switch event := subscriber.Receive().(type) {
+		case redis.Message:
+			message := new(hubMessage)
+			err := json.Unmarshal(event.Data, message)
+			if err != nil {
+				GetLogger().WithError(err).Warn("Ignoring malformed WebSocket broadcast")
+				continue
+			}
+			if message.Origin != h.id {
+				h.deliver(message.Room, message.Data)
+			}
+
+		case error:
+			return event
+		}
+	}
+}
+
+// close stops the redis subscription and closes all the connections with a
+// going away close message.
+func (h *Hub) close(ctx context.Context) error {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.stop)
+	if h.subscriber != nil {
+		h.subscriber.Unsubscribe()
+		h.subscriber.Close()
+	}
+	conns := make([]*WebSocketConn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mutex.Unlock()
+
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	for _, conn := range conns {
+		conn.writeMutex.Lock()
+		conn.socket.WriteControl(websocket.CloseMessage, message, time.Now().Add(webSocketWriteWait))
+		conn.writeMutex.Unlock()
+		conn.socket.Close()
+	}
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WebSocketConn is a WebSocket connection of the hub. Reads must be done by
+// the handler goroutine; writes are safe for concurrent use.
+type WebSocketConn struct {
+	socket     *websocket.Conn
+	hub        *Hub
+	send       chan []byte
+	ctx        *gin.Context
+	rooms      map[string]struct{}
+	writeMutex sync.Mutex
+
+	onceDone sync.Once
+	done     chan struct{}
+}
+
+// Context returns the gin context of the upgrade request, with the values
+// stored by the guards, as the UIDKey.
+func (c *WebSocketConn) Context() *gin.Context {
+	return c.ctx
+}
+
+// UID returns the authenticated user ID, if any.
+func (c *WebSocketConn) UID() string {
+	return c.ctx.GetString(UIDKey)
+}
+
+// Read reads the next message. It returns an error when the connection is
+// closed, so handlers can loop until it fails.
+func (c *WebSocketConn) Read() ([]byte, error) {
+	_, message, err := c.socket.ReadMessage()
+	return message, err
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	return c.socket.ReadJSON(v)
+}
+
+// Send queues the message to be written to the client.
+func (c *WebSocketConn) Send(message []byte) error {
+	c.hub.mutex.RLock()
+	defer c.hub.mutex.RUnlock()
+
+	// send is closed once the connection is unregistered
+	if _, ok := c.hub.conns[c]; !ok {
+		return websocket.ErrCloseSent
+	}
+
+	select {
+	case c.send <- message:
+		return nil
+	default:
+		return websocket.ErrCloseSent
+	}
+}
+
+// SendJSON queues the JSON encoding of v to be written to the client.
+func (c *WebSocketConn) SendJSON(v interface{}) error {
+	message, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(message)
+}
+
+// Join adds the connection to the room.
+func (c *WebSocketConn) Join(room string) {
+	c.hub.join(c, room)
+}
+
+// Leave removes the connection from the room.
+func (c *WebSocketConn) Leave(room string) {
+	c.hub.mutex.Lock()
+	defer c.hub.mutex.Unlock()
+	c.hub.leave(c, room)
+}
+
+func (c *WebSocketConn) writerDone() chan struct{} {
+	c.onceDone.Do(func() {
+		c.done = make(chan struct{})
+	})
+	return c.done
+}
+
+// writeLoop writes the queued messages and pings the client, so dead
+// connections are detected by the read deadline.
+func (c *WebSocketConn) writeLoop() {
+	done := c.writerDone()
+	defer close(done)
+
+	ticker := time.NewTicker(webSocketPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.write(websocket.TextMessage, message); err != nil {
+				c.socket.Close()
+				return
+			}
+
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				c.socket.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *WebSocketConn) write(messageType int, message []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.socket.SetWriteDeadline(time.Now().Add(webSocketWriteWait))
+	return c.socket.WriteMessage(messageType, message)
+}